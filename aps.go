@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type AccessPoint struct {
+	ApDeploymentNode   string   `json:"ap_deployment_mode"`
+	ApGroup            string   `json:"ap_group"`
+	ClientCount        int      `json:"client_count"`
+	ClusterId          string   `json:"cluster_id"`
+	ControllerName     string   `json:"controller_name"`
+	CpuUtilization     int      `json:"cpu_utilization"`
+	FirmwareVersion    string   `json:"firmware_version"`
+	GatewayClusterId   string   `json:"gateway_cluster_id"`
+	GatewayClusterName string   `json:"gateway_cluster_name"`
+	GroupName          string   `json:"group_name"`
+	IpAddress          string   `json:"ip_address"`
+	Labels             []string `json:"labels"`
+	LastModified       int      `json:"last_modified"`
+	MacAddress         string   `json:"macaddr"`
+	MemFree            int      `json:"mem_free"`
+	MemTotal           int      `json:"mem_total"`
+	MeshRole           string   `json:"mesh_role"`
+	Model              string   `json:"model"`
+	Name               string   `json:"name"`
+	Notes              string   `json:"notes"`
+	PublicIpAddress    string   `json:"public_ip_address"`
+	Radios             []struct {
+		Band          int    `json:"band"`
+		Channel       string `json:"channel"`
+		Index         int    `json:"index"`
+		MacAddress    string `json:"macaddr"`
+		Node          int    `json:"node"`
+		RadioName     string `json:"radio_name"`
+		RadioType     string `json:"radio_type"`
+		SpatialStream string `json:"spatial_stream"`
+		Status        string `json:"status"`
+		TxPower       int    `json:"tx_power"`
+		Utilization   int    `json:"utilization"`
+	} `json:"radios"`
+	Serial      string `json:"serial"`
+	Site        string `json:"site"`
+	SleepStatus bool   `json:"sleep_status"`
+	Status      string `json:"status"`
+	SubnetMask  string `json:"subnet_mask"`
+	SwarmId     string `json:"swarm_id"`
+	SwarmMaster bool   `json:"swarm_master"`
+	SwarmName   string `json:"swarm_name"`
+	Uptime      int    `json:"uptime"`
+}
+
+var (
+	apUp             = prometheus.NewDesc("aruba_ap_up", "Whether the access point is up", []string{"name", "serial"}, nil)
+	apClientCount    = newDeviceDesc("aruba_ap_client_count", "Number of clients connected to access point", []string{"name", "groupName", "site", "status", "firmwareVersion", "model"})
+	apCpuUtilization = newDeviceDesc("aruba_ap_cpu_utilization", "CPU Utilization of the access point in percentge", []string{"name", "groupName", "site", "status", "firmwareVersion", "model"})
+	apMemFree        = newDeviceDesc("aruba_ap_mem_free", "Amount of free memory of access point", []string{"name", "groupName", "site", "status", "firmwareVersion", "model"})
+	apMemTotal       = newDeviceDesc("aruba_ap_mem_total", "Total amount of  memory of access point", []string{"name", "groupName", "site", "status", "firmwareVersion", "model"})
+	apUptime         = newDeviceDesc("aruba_ap_uptime", "Uptime of the access point in seconds", []string{"name", "groupName", "site", "status", "firmwareVersion", "model"})
+
+	apRadioTxPower     = prometheus.NewDesc("aruba_ap_radio_tx_power", "Radio tx power", []string{"band", "channel", "radioName", "apName"}, nil)
+	apRadioUtilization = prometheus.NewDesc("aruba_ap_radio_utilization", "Radip cpu utilization", []string{"band", "channel", "radioName", "apName"}, nil)
+)
+
+type apCollector struct{}
+
+func init() {
+	registerCollector("aps", true, apCollector{})
+}
+
+func (apCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- apUp
+	ch <- apClientCount.desc()
+	ch <- apCpuUtilization.desc()
+	ch <- apMemFree.desc()
+	ch <- apMemTotal.desc()
+	ch <- apUptime.desc()
+
+	ch <- apRadioTxPower
+	ch <- apRadioUtilization
+}
+
+func (apCollector) Update(ctx context.Context, client *CentralClient, ch chan<- prometheus.Metric) error {
+
+	query := url.Values{
+		"calculate_total":        {"true"},
+		"calculate_client_count": {"true"},
+		"calculate_ssid_count":   {"true"},
+		"show_resource_details":  {"true"},
+	}
+	client.scope.apply(query)
+
+	accessPoints, err := listAll[AccessPoint](ctx, client, "monitoring/v2/aps", "aps", query, 0)
+	if err != nil {
+		return fmt.Errorf("listing access points: %w", err)
+	}
+
+	for _, a := range accessPoints {
+
+		ch <- prometheus.MustNewConstMetric(apUp, prometheus.GaugeValue, boolValue(a.Status == "Up"), a.Name, a.Serial)
+		ch <- apClientCount.metric(float64(a.ClientCount), a.Name, a.GroupName, a.Site, a.Status, a.FirmwareVersion, a.Model)
+		ch <- apCpuUtilization.metric(float64(a.CpuUtilization), a.Name, a.GroupName, a.Site, a.Status, a.FirmwareVersion, a.Model)
+		ch <- apMemFree.metric(float64(a.MemFree), a.Name, a.GroupName, a.Site, a.Status, a.FirmwareVersion, a.Model)
+		ch <- apMemTotal.metric(float64(a.MemTotal), a.Name, a.GroupName, a.Site, a.Status, a.FirmwareVersion, a.Model)
+		ch <- apUptime.metric(float64(a.Uptime), a.Name, a.GroupName, a.Site, a.Status, a.FirmwareVersion, a.Model)
+
+		for _, r := range a.Radios {
+
+			ch <- prometheus.MustNewConstMetric(apRadioTxPower, prometheus.GaugeValue, float64(r.TxPower), strconv.Itoa(r.Band), r.Channel, r.RadioName, a.Name)
+			ch <- prometheus.MustNewConstMetric(apRadioUtilization, prometheus.GaugeValue, float64(r.Utilization), strconv.Itoa(r.Band), r.Channel, r.RadioName, a.Name)
+		}
+	}
+
+	return nil
+}
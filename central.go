@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	apiRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "aruba_exporter_api_requests_total",
+		Help: "Number of requests made to the Central API by endpoint and HTTP status code",
+	}, []string{"endpoint", "code"})
+	apiRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "aruba_exporter_api_request_duration_seconds",
+		Help:    "Duration of requests to the Central API by endpoint",
+		Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"endpoint"})
+)
+
+// Errors wrapped by APIError according to the response status code.
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrServer       = errors.New("server error")
+)
+
+// APIError is returned for any non-2xx response from Central.
+type APIError struct {
+	Path       string
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // only set for 429 responses
+}
+
+func (e *APIError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("%s returned HTTP status code %d", e.Path, e.StatusCode)
+	}
+	return fmt.Sprintf("%s returned HTTP status code %d: %s", e.Path, e.StatusCode, e.Body)
+}
+
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusForbidden:
+		return ErrForbidden
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode >= http.StatusInternalServerError:
+		return ErrServer
+	}
+	return nil
+}
+
+// Wait used when a 429 response carries no Retry-After header.
+const defaultRetryAfter = time.Second
+
+// centralHTTPClient is shared by every CentralClient so that connections to
+// Central are reused across config reloads.
+var centralHTTPClient = func() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = 10
+	transport.IdleConnTimeout = 90 * time.Second
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   60 * time.Second,
+	}
+}()
+
+// Scope limits the devices the collectors fetch to one site or group. The
+// zero value covers the whole tenant.
+type Scope struct {
+	Site  string
+	Group string
+}
+
+// apply adds the scope's filters to the query of a Central list endpoint.
+func (s Scope) apply(query url.Values) {
+	if s.Site != "" {
+		query.Set("site", s.Site)
+	}
+	if s.Group != "" {
+		query.Set("group", s.Group)
+	}
+}
+
+// CentralClient calls the Aruba Central REST API on behalf of all
+// collectors.
+type CentralClient struct {
+	endpoint   string
+	tokens     *TokenManager
+	limiter    *rateLimiter
+	pagination PaginationConfig
+	scope      Scope
+	client     *http.Client
+}
+
+func NewCentralClient(endpoint string, tokens *TokenManager, limiter *rateLimiter, pagination PaginationConfig) *CentralClient {
+	return &CentralClient{
+		endpoint:   endpoint,
+		tokens:     tokens,
+		limiter:    limiter,
+		pagination: pagination,
+		client:     centralHTTPClient,
+	}
+}
+
+// withScope returns a client sharing c's tokens and rate limit whose
+// collectors only fetch the devices in scope.
+func (c *CentralClient) withScope(scope Scope) *CentralClient {
+	scoped := *c
+	scoped.scope = scope
+	return &scoped
+}
+
+// Get requests path with the given query parameters and decodes the JSON
+// response into out. A rate limited request is retried once after the delay
+// Central asks for, if ctx allows, and a request whose access token is refused
+// is retried once with a refreshed one.
+func (c *CentralClient) Get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	return c.getJSON(ctx, path, path, query, out)
+}
+
+// GetDevice is Get for an endpoint templated with {serial}, such as
+// monitoring/v1/gateways/{serial}/tunnels. The API request metrics are
+// recorded against the template so as not to create series per device.
+func (c *CentralClient) GetDevice(ctx context.Context, endpoint string, serial string, query url.Values, out interface{}) error {
+	path := strings.Replace(endpoint, "{serial}", url.PathEscape(serial), 1)
+	return c.getJSON(ctx, endpoint, path, query, out)
+}
+
+func (c *CentralClient) getJSON(ctx context.Context, endpoint string, path string, query url.Values, out interface{}) error {
+	target := c.endpoint + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	body, token, err := c.get(ctx, endpoint, path, target)
+
+	var apiErr *APIError
+	switch {
+	case errors.Is(err, ErrUnauthorized):
+		// The token was rotated by another process or loaded stale from the
+		// cache before its recorded expiry.
+		if err := c.tokens.Invalidate(ctx, token); err != nil {
+			return fmt.Errorf("refreshing refused access token: %w", err)
+		}
+		body, _, err = c.get(ctx, endpoint, path, target)
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests:
+		if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > apiErr.RetryAfter {
+			body, _, err = c.get(ctx, endpoint, path, target)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("parsing JSON from %s: %w", path, err)
+	}
+	return nil
+}
+
+// get makes a single request, returning the body of a successful response and
+// the access token it was sent with.
+func (c *CentralClient) get(ctx context.Context, endpoint string, path string, target string) ([]byte, string, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, "", err
+	}
+
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("getting access token: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("creating request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	apiRequestDuration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+	if err != nil {
+		apiRequests.WithLabelValues(endpoint, "error").Inc()
+		return nil, "", fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	apiRequests.WithLabelValues(endpoint, strconv.Itoa(resp.StatusCode)).Inc()
+
+	if verbose {
+		fmt.Println("\n"+path, "- HTTP Status Code:", resp.StatusCode)
+
+		for key, value := range resp.Header {
+			fmt.Printf(" (%s: %s),", key, value)
+		}
+	}
+
+	recordRateLimitHeaders(c.limiter.tenant, resp.Header)
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("reading response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{Path: path, StatusCode: resp.StatusCode, Body: string(body)}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			apiErr.RetryAfter = defaultRetryAfter
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+				apiErr.RetryAfter = time.Duration(seconds) * time.Second
+			}
+			c.limiter.block(apiErr.RetryAfter)
+		}
+		return nil, token, apiErr
+	}
+
+	return body, token, nil
+}
+
+// recordRateLimitHeaders exports the remaining quota Central reports with
+// every response.
+func recordRateLimitHeaders(tenant string, header http.Header) {
+	for _, period := range []string{"day", "second"} {
+		if remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining-" + period)); err == nil {
+			apiRateLimitRemaining.WithLabelValues(tenant, period).Set(float64(remaining))
+		}
+	}
+}
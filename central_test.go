@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+func TestGetRefusedToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		validToken    string // the only token the API accepts
+		wantErr       error
+		wantRequests  int32
+		wantRefreshes int32
+	}{
+		{"accepted", "Bearer access", nil, 1, 0},
+		{"rotated elsewhere", "Bearer access-1", nil, 2, 1},
+		{"still refused", "Bearer other", ErrUnauthorized, 2, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requests, refreshes int32
+			refresh := tokenHandler(&refreshes)
+
+			mux := http.NewServeMux()
+			mux.Handle("/oauth2/token", refresh)
+			mux.HandleFunc("/monitoring/v1/switches", func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				if r.Header.Get("Authorization") != test.validToken {
+					http.Error(w, `{"error":"invalid_token"}`, http.StatusUnauthorized)
+					return
+				}
+				w.Write([]byte(`{"count":1}`))
+			})
+			client := newTestCentralClient(t, mux, PaginationConfig{})
+
+			var response struct {
+				Count int `json:"count"`
+			}
+			err := client.Get(context.Background(), "monitoring/v1/switches", nil, &response)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+			if err == nil && response.Count != 1 {
+				t.Errorf("got count %d, want 1", response.Count)
+			}
+			if requests != test.wantRequests {
+				t.Errorf("got %d requests, want %d", requests, test.wantRequests)
+			}
+			if refreshes != test.wantRefreshes {
+				t.Errorf("got %d refreshes, want %d", refreshes, test.wantRefreshes)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	clientCount = prometheus.NewDesc("aruba_clients", "Number of connected clients", []string{"site", "ssid", "band", "os_type", "connection"}, nil)
+)
+
+// clientCountCollector counts the connected clients without exposing any
+// individual client. It pages through every client like the clients
+// collector, so it is disabled by default.
+type clientCountCollector struct{}
+
+func init() {
+	registerCollector("client_counts", false, clientCountCollector{})
+}
+
+func (clientCountCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- clientCount
+}
+
+func (clientCountCollector) Update(ctx context.Context, client *CentralClient, ch chan<- prometheus.Metric) error {
+
+	clients, err := listClients(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	counts := map[[5]string]int{}
+	for _, c := range clients {
+
+		connection := c.Connection
+		if connection == "" && c.ClientType == "WIRED" {
+			connection = "wired"
+		}
+
+		counts[[5]string{c.Site, c.Network, c.bandLabel(), c.OsType, connection}]++
+	}
+
+	for key, count := range counts {
+		ch <- prometheus.MustNewConstMetric(clientCount, prometheus.GaugeValue, float64(count), key[:]...)
+	}
+
+	return nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type ClientDetails struct {
+	AssociatedDevice     string  `json:"associated_device"`
+	AssociatedDeviceName string  `json:"associated_device_name"`
+	Band                 float64 `json:"band"`
+	Channel              string  `json:"channel"`
+	ClientType           string  `json:"client_type"`
+	Connection           string  `json:"connection"`
+	GroupName            string  `json:"group_name"`
+	HealthScore          int     `json:"health"`
+	InterfacePort        string  `json:"interface_port"`
+	IpAddress            string  `json:"ip_address"`
+	MacAddress           string  `json:"macaddr"`
+	Name                 string  `json:"name"`
+	Network              string  `json:"network"`
+	OsType               string  `json:"os_type"`
+	SignalDb             int     `json:"signal_db"`
+	Site                 string  `json:"site"`
+	Snr                  int     `json:"snr"`
+	Speed                int     `json:"speed"` // in Mbit/s
+	Username             string  `json:"username"`
+	Vlan                 int     `json:"vlan"`
+}
+
+var clientsAggregate = flag.Bool("collector.clients.aggregate", false, "Export client signal, SNR, speed and health as histograms per site, SSID and band instead of per client")
+
+var (
+	clientInfo   = prometheus.NewDesc("aruba_client_info", "Where the client is connected", []string{"mac", "name", "type", "site", "ssid", "band", "channel", "vlan", "connectedDevice", "port"}, nil)
+	clientSignal = prometheus.NewDesc("aruba_client_signal_db", "Signal strength of the wireless client in dB", []string{"mac", "name"}, nil)
+	clientSnr    = prometheus.NewDesc("aruba_client_snr_db", "Signal to noise ratio of the wireless client in dB", []string{"mac", "name"}, nil)
+	clientSpeed  = prometheus.NewDesc("aruba_client_speed_bytes", "Connection speed of the client in bytes per second", []string{"mac", "name"}, nil)
+	clientHealth = prometheus.NewDesc("aruba_client_health_score", "Health score of the client from 0 to 100", []string{"mac", "name"}, nil)
+
+	clientsSignal = prometheus.NewDesc("aruba_clients_signal_db", "Signal strength of the wireless clients in dB", []string{"site", "type", "ssid", "band"}, nil)
+	clientsSnr    = prometheus.NewDesc("aruba_clients_snr_db", "Signal to noise ratio of the wireless clients in dB", []string{"site", "type", "ssid", "band"}, nil)
+	clientsSpeed  = prometheus.NewDesc("aruba_clients_speed_bytes", "Connection speed of the clients in bytes per second", []string{"site", "type", "ssid", "band"}, nil)
+	clientsHealth = prometheus.NewDesc("aruba_clients_health_score", "Health score of the clients from 0 to 100", []string{"site", "type", "ssid", "band"}, nil)
+)
+
+// Histogram buckets used when aggregating.
+var (
+	signalBuckets = []float64{-90, -80, -70, -67, -60, -50}
+	snrBuckets    = []float64{10, 15, 20, 25, 30, 40}
+	speedBuckets  = []float64{10e6 / 8, 50e6 / 8, 100e6 / 8, 300e6 / 8, 600e6 / 8, 1200e6 / 8}
+	healthBuckets = []float64{25, 50, 75, 90, 100}
+)
+
+// clientCollector pages through every connected client, which makes one
+// series per client and is disabled by default.
+type clientCollector struct{}
+
+func init() {
+	registerCollector("clients", false, clientCollector{})
+}
+
+func (clientCollector) Describe(ch chan<- *prometheus.Desc) {
+	if *clientsAggregate {
+		ch <- clientsSignal
+		ch <- clientsSnr
+		ch <- clientsSpeed
+		ch <- clientsHealth
+		return
+	}
+
+	ch <- clientInfo
+	ch <- clientSignal
+	ch <- clientSnr
+	ch <- clientSpeed
+	ch <- clientHealth
+}
+
+func (clientCollector) Update(ctx context.Context, client *CentralClient, ch chan<- prometheus.Metric) error {
+
+	clients, err := listClients(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	if *clientsAggregate {
+		aggregateClients(clients, ch)
+		return nil
+	}
+
+	for _, c := range clients {
+
+		clientType := strings.ToLower(c.ClientType)
+
+		ch <- prometheus.MustNewConstMetric(clientInfo, prometheus.GaugeValue, 1, c.MacAddress, c.Name, clientType, c.Site, c.Network, c.bandLabel(), c.Channel, strconv.Itoa(c.Vlan), c.AssociatedDeviceName, c.InterfacePort)
+		if clientType == "wireless" {
+			ch <- prometheus.MustNewConstMetric(clientSignal, prometheus.GaugeValue, float64(c.SignalDb), c.MacAddress, c.Name)
+			ch <- prometheus.MustNewConstMetric(clientSnr, prometheus.GaugeValue, float64(c.Snr), c.MacAddress, c.Name)
+		}
+		ch <- prometheus.MustNewConstMetric(clientSpeed, prometheus.GaugeValue, float64(c.Speed)*1e6/8, c.MacAddress, c.Name)
+		ch <- prometheus.MustNewConstMetric(clientHealth, prometheus.GaugeValue, float64(c.HealthScore), c.MacAddress, c.Name)
+	}
+
+	return nil
+}
+
+// bandLabel returns the band in GHz, such as 2.4, or nothing for wired
+// clients.
+func (c ClientDetails) bandLabel() string {
+	if c.Band == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(c.Band, 'f', -1, 64)
+}
+
+// listClients returns the connected wireless and wired clients.
+func listClients(ctx context.Context, client *CentralClient) ([]ClientDetails, error) {
+	var clients []ClientDetails
+
+	for _, clientType := range []string{"WIRELESS", "WIRED"} {
+		query := url.Values{
+			"client_type":     {clientType},
+			"client_status":   {"CONNECTED"},
+			"calculate_total": {"true"},
+		}
+		client.scope.apply(query)
+
+		page, err := listAll[ClientDetails](ctx, client, "monitoring/v2/clients", "clients", query, 1000)
+		if err != nil {
+			return nil, fmt.Errorf("listing %s clients: %w", strings.ToLower(clientType), err)
+		}
+
+		for i := range page {
+			page[i].ClientType = clientType
+		}
+		clients = append(clients, page...)
+	}
+
+	return clients, nil
+}
+
+// clientGroup accumulates the clients of one site, type, SSID and band.
+type clientGroup struct {
+	signal, snr, speed, health histogram
+}
+
+// histogram accumulates observations for a constant histogram.
+type histogram struct {
+	buckets []float64
+	counts  map[float64]uint64
+	count   uint64
+	sum     float64
+}
+
+func newHistogram(buckets []float64) histogram {
+	h := histogram{buckets: buckets, counts: map[float64]uint64{}}
+	for _, upper := range buckets {
+		h.counts[upper] = 0
+	}
+	return h
+}
+
+func (h *histogram) observe(v float64) {
+	for _, upper := range h.buckets {
+		if v <= upper {
+			h.counts[upper]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+func (h *histogram) metric(desc *prometheus.Desc, labels ...string) prometheus.Metric {
+	return prometheus.MustNewConstHistogram(desc, h.count, h.sum, h.counts, labels...)
+}
+
+// aggregateClients sends histograms of the clients of each site, type, SSID
+// and band in place of a series per client.
+func aggregateClients(clients []ClientDetails, ch chan<- prometheus.Metric) {
+	groups := map[[4]string]*clientGroup{}
+
+	for _, c := range clients {
+
+		key := [4]string{c.Site, strings.ToLower(c.ClientType), c.Network, c.bandLabel()}
+		g, ok := groups[key]
+		if !ok {
+			g = &clientGroup{
+				signal: newHistogram(signalBuckets),
+				snr:    newHistogram(snrBuckets),
+				speed:  newHistogram(speedBuckets),
+				health: newHistogram(healthBuckets),
+			}
+			groups[key] = g
+		}
+
+		if c.ClientType == "WIRELESS" {
+			g.signal.observe(float64(c.SignalDb))
+			g.snr.observe(float64(c.Snr))
+		}
+		g.speed.observe(float64(c.Speed) * 1e6 / 8)
+		g.health.observe(float64(c.HealthScore))
+	}
+
+	for key, g := range groups {
+		if key[1] == "wireless" {
+			ch <- g.signal.metric(clientsSignal, key[:]...)
+			ch <- g.snr.metric(clientsSnr, key[:]...)
+		}
+		ch <- g.speed.metric(clientsSpeed, key[:]...)
+		ch <- g.health.metric(clientsHealth, key[:]...)
+	}
+}
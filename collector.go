@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector fetches one kind of data from Central and turns it into metrics.
+type Collector interface {
+	Describe(ch chan<- *prometheus.Desc)
+	Update(ctx context.Context, client *CentralClient, ch chan<- prometheus.Metric) error
+}
+
+// namedCollector is a Collector in the registry.
+type namedCollector struct {
+	name           string
+	defaultEnabled bool
+	collector      Collector
+
+	// Set from --collector.<name> and --no-collector.<name>.
+	enable  *bool
+	disable *bool
+}
+
+// collectors is the registry of every collector, in the order they were
+// registered.
+var collectors []*namedCollector
+
+// registerCollector adds a collector to the registry along with its
+// --collector.<name> and --no-collector.<name> flags. It must be called from
+// init.
+func registerCollector(name string, defaultEnabled bool, collector Collector) {
+	state := "disabled"
+	if defaultEnabled {
+		state = "enabled"
+	}
+
+	collectors = append(collectors, &namedCollector{
+		name:           name,
+		defaultEnabled: defaultEnabled,
+		collector:      collector,
+		enable:         flag.Bool("collector."+name, false, "Enable the "+name+" collector (default "+state+")"),
+		disable:        flag.Bool("no-collector."+name, false, "Disable the "+name+" collector"),
+	})
+}
+
+// detailsAbort reports whether err, from fetching the details of one device,
+// will fail those of every remaining device too, so that a collector making a
+// call per device should give up rather than log and skip the device.
+func detailsAbort(ctx context.Context, err error) bool {
+	return ctx.Err() != nil || errors.Is(err, ErrBudgetExhausted)
+}
+
+func knownCollector(name string) bool {
+	for _, c := range collectors {
+		if c.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// enabledCollectors returns the collectors to run. A flag given on the command
+// line takes precedence over the collectors section of the config, which takes
+// precedence over the collector's default.
+func enabledCollectors(config *Config) []*namedCollector {
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	var enabled []*namedCollector
+	for _, c := range collectors {
+		on := c.defaultEnabled
+		if configured, ok := config.Collectors[c.name]; ok {
+			on = configured
+		}
+		if set["collector."+c.name] {
+			on = *c.enable
+		}
+		if set["no-collector."+c.name] && *c.disable {
+			on = false
+		}
+
+		if on {
+			enabled = append(enabled, c)
+		}
+	}
+	return enabled
+}
+
+// dropStatusLabels removes the labels that change with a device's state from
+// the device metrics, so that a status change or upgrade does not start new
+// series.
+var dropStatusLabels = flag.Bool("device.drop-status-labels", false, "Drop the status and firmwareVersion labels from the AP, switch, mobility controller and gateway metrics")
+
+// deviceDesc describes a device metric whose status and firmwareVersion labels
+// are dropped with --device.drop-status-labels.
+type deviceDesc struct {
+	full    *prometheus.Desc
+	stable  *prometheus.Desc
+	dropped map[int]bool // indexes of the dropped labels
+}
+
+func newDeviceDesc(name, help string, labels []string) *deviceDesc {
+	d := &deviceDesc{
+		full:    prometheus.NewDesc(name, help, labels, nil),
+		dropped: map[int]bool{},
+	}
+
+	var stable []string
+	for i, l := range labels {
+		if l == "status" || l == "firmwareVersion" {
+			d.dropped[i] = true
+			continue
+		}
+		stable = append(stable, l)
+	}
+	d.stable = prometheus.NewDesc(name, help, stable, nil)
+
+	return d
+}
+
+func (d *deviceDesc) desc() *prometheus.Desc {
+	if *dropStatusLabels {
+		return d.stable
+	}
+	return d.full
+}
+
+// metric takes the values of every label and leaves out the dropped ones.
+func (d *deviceDesc) metric(value float64, labelValues ...string) prometheus.Metric {
+	if !*dropStatusLabels {
+		return prometheus.MustNewConstMetric(d.full, prometheus.GaugeValue, value, labelValues...)
+	}
+
+	var stable []string
+	for i, v := range labelValues {
+		if !d.dropped[i] {
+			stable = append(stable, v)
+		}
+	}
+	return prometheus.MustNewConstMetric(d.stable, prometheus.GaugeValue, value, stable...)
+}
+
+// boolValue converts a condition to the 1 or 0 of a Prometheus gauge.
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
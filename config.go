@@ -2,12 +2,19 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
@@ -19,51 +26,475 @@ type Response struct {
 	ExpiresIn    int    `json:"expires_in"`
 }
 
+// Current version of the config file layout. Version 1 configs, which wrote
+// every section as a list of single-key maps, are still accepted.
+const configVersion = 2
+
 type Config struct {
-	ArubaEndpoint string `yaml:"arubaEndpoint"`
-	ArubaUser     []struct {
-		ArubaUsername string `yaml:"user"`
-		ArubaPassword string `yaml:"password"`
-	} `yaml:"arubaUser"`
-	ArubaApplicationCredentials []struct {
-		ClientID     string `yaml:"clientId"`
-		ClientSecret string `yaml:"clientSecret"`
-		CustomerID   string `yaml:"customerId"`
-	} `yaml:"arubaApplicationCredentials"`
-	ExporterConfig []struct {
-		ExporterEndpoint string `yaml:"exporterEndpoint"`
-		ExporterPort     string `yaml:"exporterPort"`
-	} `yaml:"exporterConfig"`
-}
-
-func readConfig(c *Config, configPath string) {
+	Version                     int                     `yaml:"version"`
+	ArubaEndpoint               string                  `yaml:"arubaEndpoint"`
+	ArubaUser                   UserCredentials         `yaml:"arubaUser"`
+	ArubaApplicationCredentials ApplicationCredentials  `yaml:"arubaApplicationCredentials"`
+	ArubaTokens                 PreIssuedTokens         `yaml:"arubaTokens"`
+	ExporterConfig              ExporterConfig          `yaml:"exporterConfig"`
+	TokenCacheFile              string                  `yaml:"tokenCacheFile"`
+	RateLimit                   RateLimitConfig         `yaml:"rateLimit"`
+	Pagination                  PaginationConfig        `yaml:"pagination"`
+	Polling                     PollingConfig           `yaml:"polling"`
+	Collectors                  map[string]bool         `yaml:"collectors"` // overrides each collector's default
+	Tenants                     []Tenant                `yaml:"tenants"`
+	Modules                     map[string]ModuleConfig `yaml:"modules"`
+}
+
+// ModuleConfig is a set of collectors that /probe runs for a single site or
+// group.
+type ModuleConfig struct {
+	Collectors []string `yaml:"collectors"` // all enabled collectors if empty
+	TargetType string   `yaml:"targetType"` // site or group
+}
+
+// Tenant is an additional Central customer scraped through /probe. Its
+// arubaEndpoint defaults to the top-level one.
+type Tenant struct {
+	Name                        string                 `yaml:"name"`
+	ArubaEndpoint               string                 `yaml:"arubaEndpoint"`
+	ArubaUser                   UserCredentials        `yaml:"arubaUser"`
+	ArubaApplicationCredentials ApplicationCredentials `yaml:"arubaApplicationCredentials"`
+	ArubaTokens                 PreIssuedTokens        `yaml:"arubaTokens"`
+	TokenCacheFile              string                 `yaml:"tokenCacheFile"`
+}
+
+type UserCredentials struct {
+	ArubaUsername string `yaml:"user"`
+	ArubaPassword string `yaml:"password"`
+	PasswordFile  string `yaml:"passwordFile"`
+}
+
+type ApplicationCredentials struct {
+	ClientID         string `yaml:"clientId"`
+	ClientSecret     string `yaml:"clientSecret"`
+	ClientSecretFile string `yaml:"clientSecretFile"`
+	CustomerID       string `yaml:"customerId"`
+}
+
+type PreIssuedTokens struct {
+	AccessToken      string `yaml:"accessToken"`
+	AccessTokenFile  string `yaml:"accessTokenFile"`
+	RefreshToken     string `yaml:"refreshToken"`
+	RefreshTokenFile string `yaml:"refreshTokenFile"`
+}
+
+type ExporterConfig struct {
+	ExporterEndpoint        string        `yaml:"exporterEndpoint"`
+	ExporterPort            string        `yaml:"exporterPort"`
+	ReloadToken             string        `yaml:"reloadToken"`
+	ReloadTokenFile         string        `yaml:"reloadTokenFile"`
+	ScrapeTimeout           time.Duration `yaml:"scrapeTimeout"` // used when Prometheus does not send one
+	MaxConcurrentCollectors int           `yaml:"maxConcurrentCollectors"`
+}
+
+type RateLimitConfig struct {
+	RequestsPerSecond float64 `yaml:"requestsPerSecond"`
+	Burst             int     `yaml:"burst"`
+	DailyBudget       int     `yaml:"dailyBudget"` // 0 means unlimited
+}
+
+type PaginationConfig struct {
+	PageSize      int `yaml:"pageSize"`
+	ParallelPages int `yaml:"parallelPages"`
+}
+
+type PollingConfig struct {
+	Enabled   bool                     `yaml:"enabled"`
+	Interval  time.Duration            `yaml:"interval"`
+	Intervals map[string]time.Duration `yaml:"intervals"` // per collector overrides
+}
+
+// interval returns how often the named collector is polled.
+func (p PollingConfig) interval(name string) time.Duration {
+	if interval, ok := p.Intervals[name]; ok {
+		return interval
+	}
+	return p.Interval
+}
+
+func (u *UserCredentials) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain UserCredentials
+	return unmarshalSection(unmarshal, (*plain)(u))
+}
+
+func (a *ApplicationCredentials) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain ApplicationCredentials
+	return unmarshalSection(unmarshal, (*plain)(a))
+}
+
+func (p *PreIssuedTokens) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain PreIssuedTokens
+	return unmarshalSection(unmarshal, (*plain)(p))
+}
+
+func (e *ExporterConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain ExporterConfig
+	return unmarshalSection(unmarshal, (*plain)(e))
+}
+
+// unmarshalSection decodes a config section written either as a mapping or,
+// as in version 1 configs, as a list of single-key mappings. out must be a
+// pointer to a struct.
+func unmarshalSection(unmarshal func(interface{}) error, out interface{}) error {
+	err := unmarshal(out)
+	if err == nil {
+		return nil
+	}
+
+	// yaml.v2 reuses the backing array of the errors on the next call.
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		err = &yaml.TypeError{Errors: append([]string(nil), typeErr.Errors...)}
+	}
+
+	items := reflect.New(reflect.SliceOf(reflect.TypeOf(out).Elem()))
+	if unmarshal(items.Interface()) != nil {
+		return err
+	}
+
+	// Each list entry sets one field, so merge the non-zero ones.
+	merged := reflect.ValueOf(out).Elem()
+	list := items.Elem()
+	for i := 0; i < list.Len(); i++ {
+		item := list.Index(i)
+		for f := 0; f < item.NumField(); f++ {
+			if !item.Field(f).IsZero() {
+				merged.Field(f).Set(item.Field(f))
+			}
+		}
+	}
+	return nil
+}
+
+func loadConfig(configPath string) (*Config, error) {
 	// Read the YAML file
 	data, err := ioutil.ReadFile(configPath)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	// Parse the YAML data into a Config struct
-	err = yaml.Unmarshal(data, &c)
-	if err != nil {
-		log.Fatal(err)
+	c := &Config{}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", configPath, err)
 	}
 
+	if c.Version < configVersion {
+		fmt.Println(time.Now().Format(time.RFC3339), "Config file", configPath, "uses the deprecated version 1 layout, see the README to migrate it")
+	}
+
+	problems := c.resolveSecrets()
+	c.setDefaults()
+	problems = append(problems, c.problems()...)
+
+	if err := problemsError(problems); err != nil {
+		return nil, fmt.Errorf("%s: %w", configPath, err)
+	}
+
+	return c, nil
 }
 
-func authenticate(c *Config, configPath string, response *Response) {
+func (c *Config) setDefaults() {
+	if c.ExporterConfig.ExporterEndpoint == "" {
+		c.ExporterConfig.ExporterEndpoint = "/metrics"
+	}
+	if c.ExporterConfig.ExporterPort == "" {
+		c.ExporterConfig.ExporterPort = ":8080"
+	}
+	if c.ExporterConfig.ScrapeTimeout == 0 {
+		c.ExporterConfig.ScrapeTimeout = 30 * time.Second
+	}
+	if c.ExporterConfig.MaxConcurrentCollectors == 0 {
+		c.ExporterConfig.MaxConcurrentCollectors = 3
+	}
+
+	// Central allows 7 calls per second per customer by default.
+	if c.RateLimit.RequestsPerSecond == 0 {
+		c.RateLimit.RequestsPerSecond = 5
+	}
+	if c.RateLimit.Burst == 0 {
+		c.RateLimit.Burst = 5
+	}
+
+	if c.Pagination.PageSize == 0 {
+		c.Pagination.PageSize = 1000
+	}
+	if c.Pagination.ParallelPages == 0 {
+		c.Pagination.ParallelPages = 1
+	}
 
-	readConfig(c, configPath)
+	if c.Polling.Interval == 0 {
+		c.Polling.Interval = time.Minute
+	}
 
-	clientId := c.ArubaApplicationCredentials[0].ClientID
-	clientSecret := c.ArubaApplicationCredentials[1].ClientSecret
-	customerId := c.ArubaApplicationCredentials[2].CustomerID
+	for name, module := range c.Modules {
+		if module.TargetType == "" {
+			module.TargetType = "site"
+			c.Modules[name] = module
+		}
+	}
 
-	username := c.ArubaUser[0].ArubaUsername
-	password := c.ArubaUser[1].ArubaPassword
+	for i := range c.Tenants {
+		if c.Tenants[i].ArubaEndpoint == "" {
+			c.Tenants[i].ArubaEndpoint = c.ArubaEndpoint
+		}
+		if c.Tenants[i].ArubaEndpoint != "" {
+			c.Tenants[i].ArubaEndpoint = strings.TrimSuffix(c.Tenants[i].ArubaEndpoint, "/") + "/"
+		}
+	}
+
+	// API paths are appended to the endpoint without a leading slash.
+	if c.ArubaEndpoint != "" {
+		c.ArubaEndpoint = strings.TrimSuffix(c.ArubaEndpoint, "/") + "/"
+	}
+}
+
+// hasDefaultTenant reports whether the top-level credentials are to be
+// scraped through the metrics endpoint. They are required unless tenants are
+// listed.
+func (c *Config) hasDefaultTenant() bool {
+	return len(c.Tenants) == 0 ||
+		c.ArubaUser != (UserCredentials{}) ||
+		c.ArubaApplicationCredentials != (ApplicationCredentials{}) ||
+		c.ArubaTokens != (PreIssuedTokens{})
+}
+
+// tenantConfigs returns the config to log in with for every tenant, keyed by
+// name, with the top-level credentials under "".
+func (c *Config) tenantConfigs() map[string]*Config {
+	configs := map[string]*Config{}
+	if c.hasDefaultTenant() {
+		configs[""] = c
+	}
+	for _, t := range c.Tenants {
+		configs[t.Name] = c.tenant(t)
+	}
+	return configs
+}
+
+// tenant returns a copy of the config that logs in as t instead.
+func (c *Config) tenant(t Tenant) *Config {
+	tc := *c
+	tc.ArubaEndpoint = t.ArubaEndpoint
+	tc.ArubaUser = t.ArubaUser
+	tc.ArubaApplicationCredentials = t.ArubaApplicationCredentials
+	tc.ArubaTokens = t.ArubaTokens
+	tc.TokenCacheFile = t.TokenCacheFile
+	tc.Tenants = nil
+	return &tc
+}
+
+// configProblem describes one semantic error in the config.
+type configProblem struct {
+	field   string // dotted path of the offending key
+	message string
+}
+
+func (p configProblem) String() string {
+	return p.field + ": " + p.message
+}
+
+// problems returns everything wrong with the config rather than stopping at
+// the first error, so that all of it can be fixed in one go.
+func (c *Config) problems() []configProblem {
+	var problems []configProblem
+	missing := func(field string) {
+		problems = append(problems, configProblem{field, "required field is missing"})
+	}
+
+	if c.Version > configVersion {
+		problems = append(problems, configProblem{"version", fmt.Sprintf("unsupported config version %d", c.Version)})
+	}
+
+	// Tenants sharing a token cache would overwrite each other's tokens.
+	cacheFiles := map[string]string{}
+	if c.hasDefaultTenant() {
+		problems = append(problems, c.credentialProblems("")...)
+		if c.TokenCacheFile != "" {
+			cacheFiles[filepath.Clean(c.TokenCacheFile)] = "tokenCacheFile"
+		}
+	}
+
+	names := map[string]bool{}
+	for i, t := range c.Tenants {
+		prefix := fmt.Sprintf("tenants[%d].", i)
+		if t.Name == "" {
+			missing(prefix + "name")
+		} else if names[t.Name] {
+			problems = append(problems, configProblem{prefix + "name", fmt.Sprintf("duplicate tenant name %q", t.Name)})
+		}
+		names[t.Name] = true
+
+		problems = append(problems, c.tenant(t).credentialProblems(prefix)...)
+
+		if t.TokenCacheFile != "" {
+			file := filepath.Clean(t.TokenCacheFile)
+			if other, ok := cacheFiles[file]; ok {
+				problems = append(problems, configProblem{prefix + "tokenCacheFile", fmt.Sprintf("%q is already used by %s", t.TokenCacheFile, other)})
+			} else {
+				cacheFiles[file] = prefix + "tokenCacheFile"
+			}
+		}
+	}
+
+	if !strings.HasPrefix(c.ExporterConfig.ExporterEndpoint, "/") {
+		problems = append(problems, configProblem{"exporterConfig.exporterEndpoint", fmt.Sprintf("%q must start with /", c.ExporterConfig.ExporterEndpoint)})
+	}
+	if _, port, err := net.SplitHostPort(c.ExporterConfig.ExporterPort); err != nil {
+		problems = append(problems, configProblem{"exporterConfig.exporterPort", fmt.Sprintf("%q is not a [host]:port address", c.ExporterConfig.ExporterPort)})
+	} else if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		problems = append(problems, configProblem{"exporterConfig.exporterPort", fmt.Sprintf("%q is not a valid port number", port)})
+	}
+
+	if c.ExporterConfig.ScrapeTimeout < 0 {
+		problems = append(problems, configProblem{"exporterConfig.scrapeTimeout", "must be positive"})
+	}
+	if c.ExporterConfig.MaxConcurrentCollectors < 0 {
+		problems = append(problems, configProblem{"exporterConfig.maxConcurrentCollectors", "must not be negative"})
+	}
+
+	if c.RateLimit.RequestsPerSecond < 0 {
+		problems = append(problems, configProblem{"rateLimit.requestsPerSecond", "must not be negative"})
+	}
+	if c.RateLimit.Burst < 0 {
+		problems = append(problems, configProblem{"rateLimit.burst", "must not be negative"})
+	}
+	if c.RateLimit.DailyBudget < 0 {
+		problems = append(problems, configProblem{"rateLimit.dailyBudget", "must not be negative"})
+	}
+
+	if c.Pagination.PageSize < 0 {
+		problems = append(problems, configProblem{"pagination.pageSize", "must not be negative"})
+	}
+	if c.Pagination.ParallelPages < 0 {
+		problems = append(problems, configProblem{"pagination.parallelPages", "must not be negative"})
+	}
+
+	if c.Polling.Interval < 0 {
+		problems = append(problems, configProblem{"polling.interval", "must be positive"})
+	}
+	for name, interval := range c.Polling.Intervals {
+		if !knownCollector(name) {
+			problems = append(problems, configProblem{"polling.intervals." + name, "unknown collector " + name})
+		} else if interval <= 0 {
+			problems = append(problems, configProblem{"polling.intervals." + name, "must be positive"})
+		}
+	}
+
+	for name := range c.Collectors {
+		if !knownCollector(name) {
+			problems = append(problems, configProblem{"collectors." + name, "unknown collector " + name})
+		}
+	}
+
+	for name, module := range c.Modules {
+		for _, collector := range module.Collectors {
+			if !knownCollector(collector) {
+				problems = append(problems, configProblem{"modules." + name + ".collectors", "unknown collector " + collector})
+			}
+		}
+		if module.TargetType != "site" && module.TargetType != "group" {
+			problems = append(problems, configProblem{"modules." + name + ".targetType", fmt.Sprintf("%q must be site or group", module.TargetType)})
+		}
+	}
+
+	return problems
+}
+
+// credentialProblems checks the endpoint and credentials used to log in,
+// prefixing the reported fields with prefix.
+func (c *Config) credentialProblems(prefix string) []configProblem {
+	var problems []configProblem
+	missing := func(field string) {
+		problems = append(problems, configProblem{field, "required field is missing"})
+	}
+
+	if c.ArubaEndpoint == "" {
+		missing(prefix + "arubaEndpoint")
+	} else if u, err := url.Parse(c.ArubaEndpoint); err != nil {
+		problems = append(problems, configProblem{prefix + "arubaEndpoint", err.Error()})
+	} else if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		problems = append(problems, configProblem{prefix + "arubaEndpoint", fmt.Sprintf("%q is not an http(s) URL", c.ArubaEndpoint)})
+	}
+
+	if c.ArubaApplicationCredentials.ClientID == "" {
+		missing(prefix + "arubaApplicationCredentials.clientId")
+	}
+	if c.ArubaApplicationCredentials.ClientSecret == "" {
+		missing(prefix + "arubaApplicationCredentials.clientSecret")
+	}
+
+	// The user is only optional when pre-issued tokens are supplied instead.
+	if c.ArubaTokens.RefreshToken == "" || c.ArubaUser != (UserCredentials{}) {
+		if c.ArubaUser.ArubaUsername == "" {
+			missing(prefix + "arubaUser.user")
+		}
+		if c.ArubaUser.ArubaPassword == "" {
+			missing(prefix + "arubaUser.password")
+		}
+		if c.ArubaApplicationCredentials.CustomerID == "" {
+			missing(prefix + "arubaApplicationCredentials.customerId")
+		}
+	}
+	if c.ArubaTokens.AccessToken != "" && c.ArubaTokens.RefreshToken == "" {
+		missing(prefix + "arubaTokens.refreshToken")
+	}
+
+	return problems
+}
+
+// problemsError returns an error listing every problem, or nil if there are
+// none.
+func problemsError(problems []configProblem) error {
+	if len(problems) == 0 {
+		return nil
+	}
+
+	messages := make([]string, len(problems))
+	for i, p := range problems {
+		messages[i] = p.String()
+	}
+	return errors.New(strings.Join(messages, "; "))
+}
+
+// sameCredentials reports whether o would log in to the same Central tenant
+// in the same way, so that the existing tokens can be kept.
+func (c *Config) sameCredentials(o *Config) bool {
+	return c.ArubaEndpoint == o.ArubaEndpoint &&
+		c.ArubaUser == o.ArubaUser &&
+		c.ArubaApplicationCredentials == o.ArubaApplicationCredentials &&
+		c.ArubaTokens == o.ArubaTokens &&
+		c.TokenCacheFile == o.TokenCacheFile
+}
+
+// hasUserCredentials reports whether the config contains a user the exporter
+// can log in with, as opposed to only pre-issued tokens.
+func (c *Config) hasUserCredentials() bool {
+	return c.ArubaUser.ArubaUsername != "" && c.ArubaUser.ArubaPassword != ""
+}
+
+func authenticate(ctx context.Context, c *Config) (*Response, error) {
+
+	if !c.hasUserCredentials() {
+		return nil, errors.New("no arubaUser credentials configured to log in with")
+	}
+
+	clientId := c.ArubaApplicationCredentials.ClientID
+	clientSecret := c.ArubaApplicationCredentials.ClientSecret
+	customerId := c.ArubaApplicationCredentials.CustomerID
+
+	username := c.ArubaUser.ArubaUsername
+	password := c.ArubaUser.ArubaPassword
+
+	client := &http.Client{Timeout: 30 * time.Second}
 
 	// First request to get the session and CSRF token
-	url := c.ArubaEndpoint + "/oauth2/authorize/central/api/login?client_id=" + clientId
+	url := c.ArubaEndpoint + "oauth2/authorize/central/api/login?client_id=" + clientId
 
 	reqBodyValues := map[string]string{
 		"username": username,
@@ -71,14 +502,20 @@ func authenticate(c *Config, configPath string, response *Response) {
 	}
 	requestBody, _ := json.Marshal(reqBodyValues)
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(requestBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(requestBody))
 	if err != nil {
-		log.Fatalf("Error sending POST request: %v", err)
+		return nil, fmt.Errorf("creating login request: %w", err)
 	}
-	defer resp.Body.Close()
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sending login request: %w", err)
+	}
+	resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		log.Fatalf("Login failed with status code: %d", resp.StatusCode)
+		return nil, fmt.Errorf("login failed with status code: %d", resp.StatusCode)
 	}
 
 	cookies := resp.Header["Set-Cookie"]
@@ -99,46 +536,45 @@ func authenticate(c *Config, configPath string, response *Response) {
 	}
 
 	// Second request to get the authorization code
-	url = c.ArubaEndpoint + "/oauth2/authorize/central/api?client_id=" + clientId + "&response_type=code&scope=all"
+	url = c.ArubaEndpoint + "oauth2/authorize/central/api?client_id=" + clientId + "&response_type=code&scope=all"
 	reqBodyValues = map[string]string{"customer_id": customerId}
 	requestBody, _ = json.Marshal(reqBodyValues)
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(requestBody))
+	req, err = http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(requestBody))
 	if err != nil {
-		log.Fatalf("Error creating request: %v", err)
+		return nil, fmt.Errorf("creating authorization request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Cookie", "session="+session)
 	req.Header.Set("X-CSRF-Token", csrftoken)
 
-	client := &http.Client{}
 	resp, err = client.Do(req)
 	if err != nil {
-		log.Fatalf("Error sending POST request: %v", err)
+		return nil, fmt.Errorf("sending authorization request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		log.Fatalf("Error reading response body: %v", err)
+		return nil, fmt.Errorf("reading authorization response body: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		log.Fatalf("Authorization request failed with status code: %d", resp.StatusCode)
+		return nil, fmt.Errorf("authorization request failed with status code: %d", resp.StatusCode)
 	}
 
 	// Extract the authorization code from the response
 	var responseData map[string]interface{}
 	if err := json.Unmarshal(body, &responseData); err != nil {
-		log.Fatalf("Error unmarshaling response body: %v", err)
+		return nil, fmt.Errorf("unmarshaling authorization response body: %w", err)
 	}
 	code, ok := responseData["auth_code"].(string)
 	if !ok {
-		log.Fatalf("Authorization code not found in response")
+		return nil, errors.New("authorization code not found in response")
 	}
 
 	// Third request to get the access token
-	url = c.ArubaEndpoint + "/oauth2/token"
+	url = c.ArubaEndpoint + "oauth2/token"
 	reqBodyValues = map[string]string{
 		"client_id":     clientId,
 		"client_secret": clientSecret,
@@ -147,26 +583,35 @@ func authenticate(c *Config, configPath string, response *Response) {
 	}
 	requestBody, _ = json.Marshal(reqBodyValues)
 
-	resp, err = http.Post(url, "application/json", bytes.NewBuffer(requestBody))
+	req, err = http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("creating token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
 
+	resp, err = client.Do(req)
 	if err != nil {
-		log.Fatalf("Error sending POST request: %v", err)
+		return nil, fmt.Errorf("sending token request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err = ioutil.ReadAll(resp.Body)
 	if err != nil {
-		log.Fatalf("Error reading response body: %v", err)
+		return nil, fmt.Errorf("reading token response body: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		log.Fatalf("Token request failed with status code: %d", resp.StatusCode)
+		return nil, fmt.Errorf("token request failed with status code: %d", resp.StatusCode)
 	}
 
-	err = json.Unmarshal([]byte(body), &response)
-	if err != nil {
-		fmt.Println("Error parsing JSON:", err)
-		return
+	response := &Response{}
+	if err := json.Unmarshal(body, response); err != nil {
+		return nil, fmt.Errorf("parsing token response: %w", err)
+	}
+
+	if response.AccessToken == "" {
+		return nil, errors.New("no access token in token response")
 	}
 
+	return response, nil
 }
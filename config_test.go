@@ -0,0 +1,192 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestUnmarshalSection(t *testing.T) {
+	want := Config{
+		ArubaEndpoint: "https://apigw-eucentral3.central.arubanetworks.com/",
+		ArubaUser:     UserCredentials{ArubaUsername: "user@example.com", ArubaPassword: "secret"},
+		ArubaApplicationCredentials: ApplicationCredentials{
+			ClientID:     "client",
+			ClientSecret: "client-secret",
+			CustomerID:   "customer",
+		},
+		ExporterConfig: ExporterConfig{
+			ExporterEndpoint: "/metrics",
+			ExporterPort:     "8080",
+			ScrapeTimeout:    20 * time.Second,
+		},
+	}
+
+	tests := []struct {
+		name    string
+		config  string
+		version int
+	}{
+		{
+			name:    "mapping",
+			version: 2,
+			config: `version: 2
+arubaEndpoint: https://apigw-eucentral3.central.arubanetworks.com/
+arubaUser:
+  user: user@example.com
+  password: secret
+arubaApplicationCredentials:
+  clientId: client
+  clientSecret: client-secret
+  customerId: customer
+exporterConfig:
+  exporterEndpoint: /metrics
+  exporterPort: "8080"
+  scrapeTimeout: 20s
+`,
+		},
+		{
+			name: "legacy list of maps",
+			config: `arubaEndpoint: https://apigw-eucentral3.central.arubanetworks.com/
+arubaUser:
+  - user: user@example.com
+  - password: secret
+arubaApplicationCredentials:
+  - clientId: client
+  - clientSecret: client-secret
+  - customerId: customer
+exporterConfig:
+  - exporterEndpoint: /metrics
+  - exporterPort: "8080"
+  - scrapeTimeout: 20s
+`,
+		},
+		{
+			name: "legacy entry with several keys",
+			config: `arubaEndpoint: https://apigw-eucentral3.central.arubanetworks.com/
+arubaUser:
+  - user: user@example.com
+    password: secret
+arubaApplicationCredentials:
+  - clientId: client
+    clientSecret: client-secret
+  - customerId: customer
+exporterConfig:
+  - exporterEndpoint: /metrics
+  - exporterPort: "8080"
+    scrapeTimeout: 20s
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var c Config
+			if err := yaml.Unmarshal([]byte(test.config), &c); err != nil {
+				t.Fatal(err)
+			}
+
+			expected := want
+			expected.Version = test.version
+			if !reflect.DeepEqual(c, expected) {
+				t.Errorf("got %+v, want %+v", c, expected)
+			}
+		})
+	}
+}
+
+func TestUnmarshalSectionErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{"scalar section", "arubaUser: user@example.com\n"},
+		{"mapping with wrong type", "exporterConfig:\n  scrapeTimeout: [20s]\n"},
+		{"list with wrong type", "exporterConfig:\n  - scrapeTimeout: soon\n"},
+		{"list of scalars", "arubaUser:\n  - user@example.com\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var c Config
+			if err := yaml.Unmarshal([]byte(test.config), &c); err == nil {
+				t.Errorf("got %+v, want an error", c)
+			}
+		})
+	}
+}
+
+func TestUnmarshalSectionStrict(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{"mapping", "arubaUser:\n  user: user@example.com\n  pasword: secret\n"},
+		{"legacy list of maps", "arubaUser:\n  - user: user@example.com\n  - pasword: secret\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var c Config
+			if err := yaml.UnmarshalStrict([]byte(test.config), &c); err == nil {
+				t.Errorf("unknown key accepted, got %+v", c)
+			}
+		})
+	}
+}
+
+func TestProblemsSharedTokenCacheFile(t *testing.T) {
+	user := UserCredentials{ArubaUsername: "user@example.com", ArubaPassword: "secret"}
+
+	tests := []struct {
+		name   string
+		config Config
+		want   []configProblem
+	}{
+		{
+			name: "separate files",
+			config: Config{TokenCacheFile: "/tokens/default.json", ArubaUser: user, Tenants: []Tenant{
+				{Name: "first", TokenCacheFile: "/tokens/first.json"},
+				{Name: "second", TokenCacheFile: "/tokens/second.json"},
+			}},
+		},
+		{
+			name: "two tenants",
+			config: Config{Tenants: []Tenant{
+				{Name: "first", TokenCacheFile: "/tokens/shared.json"},
+				{Name: "second", TokenCacheFile: "/tokens/../tokens/shared.json"},
+			}},
+			want: []configProblem{{"tenants[1].tokenCacheFile", `"/tokens/../tokens/shared.json" is already used by tenants[0].tokenCacheFile`}},
+		},
+		{
+			name: "tenant and top level",
+			config: Config{TokenCacheFile: "/tokens/shared.json", ArubaUser: user, Tenants: []Tenant{
+				{Name: "first", TokenCacheFile: "/tokens/shared.json"},
+			}},
+			want: []configProblem{{"tenants[0].tokenCacheFile", `"/tokens/shared.json" is already used by tokenCacheFile`}},
+		},
+		{
+			name: "unused top level",
+			config: Config{TokenCacheFile: "/tokens/shared.json", Tenants: []Tenant{
+				{Name: "first", TokenCacheFile: "/tokens/shared.json"},
+			}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []configProblem
+			for _, p := range test.config.problems() {
+				if strings.HasSuffix(p.field, "tokenCacheFile") {
+					got = append(got, p)
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
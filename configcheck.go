@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// yaml.v2 names the Go type in unknown field errors, which means nothing to
+// someone editing the config.
+var yamlTypeSuffix = regexp.MustCompile(` in type [\w.]+$`)
+
+// checkConfig loads the config strictly, printing every syntax, unknown key
+// and validation problem it finds. It returns false if there were any.
+func checkConfig(configPath string) bool {
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+
+	var problems []string
+
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		typeErr, ok := err.(*yaml.TypeError)
+		if !ok {
+			// Not even valid YAML, so there is nothing left to check.
+			fmt.Printf("%s: %s\n", configPath, strings.TrimPrefix(err.Error(), "yaml: "))
+			return false
+		}
+		for _, e := range typeErr.Errors {
+			problems = append(problems, yamlTypeSuffix.ReplaceAllString(e, ""))
+		}
+	}
+
+	configProblems := c.resolveSecrets()
+	c.setDefaults()
+	configProblems = append(configProblems, c.problems()...)
+
+	for _, p := range configProblems {
+		if line := keyLine(data, p.field); line > 0 {
+			problems = append(problems, fmt.Sprintf("line %d: %s", line, p))
+		} else {
+			problems = append(problems, p.String())
+		}
+	}
+
+	for _, p := range problems {
+		fmt.Printf("%s: %s\n", configPath, p)
+	}
+
+	if len(problems) > 0 {
+		fmt.Printf("%s: %d problem(s) found\n", configPath, len(problems))
+		return false
+	}
+
+	fmt.Printf("%s: OK\n", configPath)
+	return true
+}
+
+// A path segment selecting an entry of a list, such as tenants[2].
+var listIndex = regexp.MustCompile(`^(\w+)\[(\d+)\]$`)
+
+// keyLine returns the 1-based line on which the dotted key path is set, or 0
+// if it is not present. It understands both the mapping and the legacy list
+// layout of a section, and list entries selected with key[index].
+func keyLine(data []byte, path string) int {
+	lines := strings.Split(string(data), "\n")
+	start := 0
+	line := 0
+
+	for _, key := range strings.Split(path, ".") {
+		index := -1
+		if m := listIndex.FindStringSubmatch(key); m != nil {
+			key = m[1]
+			index, _ = strconv.Atoi(m[2])
+		}
+
+		pattern := regexp.MustCompile(`^\s*(-\s+)?` + regexp.QuoteMeta(key) + `\s*:`)
+		found := false
+		for i := start; i < len(lines); i++ {
+			if pattern.MatchString(lines[i]) {
+				start, line, found = i+1, i+1, true
+				break
+			}
+		}
+		if !found {
+			return 0
+		}
+
+		if index >= 0 {
+			entry := listEntry(lines, start, index)
+			if entry < 0 {
+				return 0
+			}
+			// The entry's first key shares its line with the dash.
+			start, line = entry, entry+1
+		}
+	}
+	return line
+}
+
+// listEntry returns the 0-based line on which the index-th entry of the list
+// starting at line start begins, or -1 if there is no such entry.
+func listEntry(lines []string, start int, index int) int {
+	indent := -1
+	for i := start; i < len(lines); i++ {
+		trimmed := strings.TrimLeft(lines[i], " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		lineIndent := len(lines[i]) - len(trimmed)
+		if indent < 0 {
+			indent = lineIndent
+		}
+		if lineIndent < indent || (lineIndent == indent && !strings.HasPrefix(trimmed, "-")) {
+			break
+		}
+		if lineIndent == indent {
+			if index == 0 {
+				return i
+			}
+			index--
+		}
+	}
+	return -1
+}
@@ -0,0 +1,61 @@
+package main
+
+import "testing"
+
+func TestKeyLine(t *testing.T) {
+	config := `version: 2
+arubaEndpoint: https://apigw-eucentral3.central.arubanetworks.com/
+arubaUser:
+  user: user@example.com
+  password: secret
+exporterConfig:
+  - exporterEndpoint: /metrics
+  - exporterPort: "8080"
+tenants:
+  - name: first
+    arubaUser:
+      user: first@example.com
+
+  # The second tenant logs in with tokens.
+  - name: second
+    arubaEndpoint: https://apigw-uswest4.central.arubanetworks.com/
+    arubaTokens:
+      refreshToken: token
+  -
+    arubaEndpoint: not a url
+collectors:
+  sites: false
+`
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"version", 1},
+		{"arubaUser", 3},
+		{"arubaUser.password", 5},
+		{"exporterConfig.exporterPort", 8},
+		{"tenants", 9},
+		{"tenants[0]", 10},
+		{"tenants[0].name", 10},
+		{"tenants[0].arubaUser.user", 12},
+		{"tenants[1]", 15},
+		{"tenants[1].name", 15},
+		{"tenants[1].arubaEndpoint", 16},
+		{"tenants[1].arubaTokens.refreshToken", 18},
+		{"tenants[2]", 19},
+		{"tenants[2].arubaEndpoint", 20},
+		{"tenants[3]", 0},
+		{"collectors.sites", 22},
+		{"arubaUser.passwordFile", 0},
+		{"pagination", 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			if got := keyLine([]byte(config), test.path); got != test.want {
+				t.Errorf("got line %d, want %d", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+var envReference = regexp.MustCompile(`\$\{(\w+)\}`)
+
+// envOverrides maps the ARUBA_* environment variables to the config fields
+// they replace.
+func (c *Config) envOverrides() map[string]*string {
+	return map[string]*string{
+		"ARUBA_ENDPOINT":          &c.ArubaEndpoint,
+		"ARUBA_USER":              &c.ArubaUser.ArubaUsername,
+		"ARUBA_PASSWORD":          &c.ArubaUser.ArubaPassword,
+		"ARUBA_CLIENT_ID":         &c.ArubaApplicationCredentials.ClientID,
+		"ARUBA_CLIENT_SECRET":     &c.ArubaApplicationCredentials.ClientSecret,
+		"ARUBA_CUSTOMER_ID":       &c.ArubaApplicationCredentials.CustomerID,
+		"ARUBA_ACCESS_TOKEN":      &c.ArubaTokens.AccessToken,
+		"ARUBA_REFRESH_TOKEN":     &c.ArubaTokens.RefreshToken,
+		"ARUBA_EXPORTER_ENDPOINT": &c.ExporterConfig.ExporterEndpoint,
+		"ARUBA_EXPORTER_PORT":     &c.ExporterConfig.ExporterPort,
+		"ARUBA_RELOAD_TOKEN":      &c.ExporterConfig.ReloadToken,
+		"ARUBA_TOKEN_CACHE_FILE":  &c.TokenCacheFile,
+	}
+}
+
+type secretFile struct {
+	field       string // path of the field holding the file name
+	file, value *string
+}
+
+// secretFiles lists the fields that can be read from a file instead.
+func (c *Config) secretFiles() []secretFile {
+	files := []secretFile{
+		{"arubaUser.passwordFile", &c.ArubaUser.PasswordFile, &c.ArubaUser.ArubaPassword},
+		{"arubaApplicationCredentials.clientSecretFile", &c.ArubaApplicationCredentials.ClientSecretFile, &c.ArubaApplicationCredentials.ClientSecret},
+		{"arubaTokens.accessTokenFile", &c.ArubaTokens.AccessTokenFile, &c.ArubaTokens.AccessToken},
+		{"arubaTokens.refreshTokenFile", &c.ArubaTokens.RefreshTokenFile, &c.ArubaTokens.RefreshToken},
+		{"exporterConfig.reloadTokenFile", &c.ExporterConfig.ReloadTokenFile, &c.ExporterConfig.ReloadToken},
+	}
+
+	for i := range c.Tenants {
+		t := &c.Tenants[i]
+		prefix := fmt.Sprintf("tenants[%d].", i)
+		files = append(files,
+			secretFile{prefix + "arubaUser.passwordFile", &t.ArubaUser.PasswordFile, &t.ArubaUser.ArubaPassword},
+			secretFile{prefix + "arubaApplicationCredentials.clientSecretFile", &t.ArubaApplicationCredentials.ClientSecretFile, &t.ArubaApplicationCredentials.ClientSecret},
+			secretFile{prefix + "arubaTokens.accessTokenFile", &t.ArubaTokens.AccessTokenFile, &t.ArubaTokens.AccessToken},
+			secretFile{prefix + "arubaTokens.refreshTokenFile", &t.ArubaTokens.RefreshTokenFile, &t.ArubaTokens.RefreshToken},
+		)
+	}
+	return files
+}
+
+// resolveSecrets fills in the config from ${VAR} references, *File fields and
+// ARUBA_* environment variables, in that order of precedence from lowest to
+// highest.
+func (c *Config) resolveSecrets() []configProblem {
+	var problems []configProblem
+
+	expandEnv(reflect.ValueOf(c).Elem(), "", &problems)
+
+	for _, secret := range c.secretFiles() {
+		if *secret.file == "" {
+			continue
+		}
+		if *secret.value != "" {
+			problems = append(problems, configProblem{secret.field, "cannot be combined with " + strings.TrimSuffix(secret.field, "File")})
+			continue
+		}
+		data, err := ioutil.ReadFile(*secret.file)
+		if err != nil {
+			problems = append(problems, configProblem{secret.field, err.Error()})
+			continue
+		}
+		*secret.value = strings.TrimSpace(string(data))
+	}
+
+	for name, field := range c.envOverrides() {
+		if value, ok := os.LookupEnv(name); ok {
+			*field = value
+		}
+	}
+
+	return problems
+}
+
+// expandEnv replaces ${VAR} references in every string in v with the value
+// of the environment variable, reporting any that are not set.
+func expandEnv(v reflect.Value, path string, problems *[]configProblem) {
+	switch v.Kind() {
+	case reflect.String:
+		expanded := envReference.ReplaceAllStringFunc(v.String(), func(ref string) string {
+			name := envReference.FindStringSubmatch(ref)[1]
+			value, ok := os.LookupEnv(name)
+			if !ok {
+				*problems = append(*problems, configProblem{path, fmt.Sprintf("environment variable %s is not set", name)})
+			}
+			return value
+		})
+		v.SetString(expanded)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			name := strings.Split(v.Type().Field(i).Tag.Get("yaml"), ",")[0]
+			if path != "" {
+				name = path + "." + name
+			}
+			expandEnv(v.Field(i), name, problems)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			expandEnv(v.Index(i), fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"password":        "file-password\n",
+		"tenant-password": "tenant-file-password\n",
+		"secret":          "file-secret",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		config       Config
+		env          map[string]string
+		field        func(c *Config) string
+		want         string
+		wantProblems []configProblem
+	}{
+		{
+			name:   "env reference",
+			config: Config{ArubaUser: UserCredentials{ArubaPassword: "${PASSWORD}"}},
+			env:    map[string]string{"PASSWORD": "env-password"},
+			field:  func(c *Config) string { return c.ArubaUser.ArubaPassword },
+			want:   "env-password",
+		},
+		{
+			name:   "env reference within a value",
+			config: Config{ArubaEndpoint: "https://${REGION}.central.arubanetworks.com/"},
+			env:    map[string]string{"REGION": "apigw-eucentral3"},
+			field:  func(c *Config) string { return c.ArubaEndpoint },
+			want:   "https://apigw-eucentral3.central.arubanetworks.com/",
+		},
+		{
+			name:         "unset env reference",
+			config:       Config{ArubaUser: UserCredentials{ArubaPassword: "${PASSWORD}"}},
+			field:        func(c *Config) string { return c.ArubaUser.ArubaPassword },
+			want:         "",
+			wantProblems: []configProblem{{"arubaUser.password", "environment variable PASSWORD is not set"}},
+		},
+		{
+			name:   "file",
+			config: Config{ArubaUser: UserCredentials{PasswordFile: filepath.Join(dir, "password")}},
+			field:  func(c *Config) string { return c.ArubaUser.ArubaPassword },
+			want:   "file-password",
+		},
+		{
+			name:   "file named by env reference",
+			config: Config{ArubaApplicationCredentials: ApplicationCredentials{ClientSecretFile: "${SECRETS}/secret"}},
+			env:    map[string]string{"SECRETS": dir},
+			field:  func(c *Config) string { return c.ArubaApplicationCredentials.ClientSecret },
+			want:   "file-secret",
+		},
+		{
+			name:         "value and file",
+			config:       Config{ArubaUser: UserCredentials{ArubaPassword: "password", PasswordFile: filepath.Join(dir, "password")}},
+			field:        func(c *Config) string { return c.ArubaUser.ArubaPassword },
+			want:         "password",
+			wantProblems: []configProblem{{"arubaUser.passwordFile", "cannot be combined with arubaUser.password"}},
+		},
+		{
+			name:         "env reference and file",
+			config:       Config{ArubaUser: UserCredentials{ArubaPassword: "${PASSWORD}", PasswordFile: filepath.Join(dir, "password")}},
+			env:          map[string]string{"PASSWORD": "env-password"},
+			field:        func(c *Config) string { return c.ArubaUser.ArubaPassword },
+			want:         "env-password",
+			wantProblems: []configProblem{{"arubaUser.passwordFile", "cannot be combined with arubaUser.password"}},
+		},
+		{
+			name:   "ARUBA_ variable over file",
+			config: Config{ArubaUser: UserCredentials{PasswordFile: filepath.Join(dir, "password")}},
+			env:    map[string]string{"ARUBA_PASSWORD": "aruba-password"},
+			field:  func(c *Config) string { return c.ArubaUser.ArubaPassword },
+			want:   "aruba-password",
+		},
+		{
+			name:   "ARUBA_ variable over env reference",
+			config: Config{ArubaUser: UserCredentials{ArubaPassword: "${PASSWORD}"}},
+			env:    map[string]string{"PASSWORD": "env-password", "ARUBA_PASSWORD": "aruba-password"},
+			field:  func(c *Config) string { return c.ArubaUser.ArubaPassword },
+			want:   "aruba-password",
+		},
+		{
+			name:   "empty ARUBA_ variable",
+			config: Config{ArubaUser: UserCredentials{ArubaPassword: "password"}},
+			env:    map[string]string{"ARUBA_PASSWORD": ""},
+			field:  func(c *Config) string { return c.ArubaUser.ArubaPassword },
+			want:   "",
+		},
+		{
+			name:         "missing file",
+			config:       Config{ArubaUser: UserCredentials{PasswordFile: filepath.Join(dir, "missing")}},
+			field:        func(c *Config) string { return c.ArubaUser.ArubaPassword },
+			want:         "",
+			wantProblems: []configProblem{{"arubaUser.passwordFile", "open " + filepath.Join(dir, "missing") + ": no such file or directory"}},
+		},
+		{
+			name: "tenant file",
+			config: Config{Tenants: []Tenant{
+				{Name: "first"},
+				{Name: "second", ArubaUser: UserCredentials{PasswordFile: filepath.Join(dir, "tenant-password")}},
+			}},
+			field: func(c *Config) string { return c.Tenants[1].ArubaUser.ArubaPassword },
+			want:  "tenant-file-password",
+		},
+		{
+			name: "tenant value and file",
+			config: Config{Tenants: []Tenant{
+				{Name: "first"},
+				{Name: "second", ArubaUser: UserCredentials{ArubaPassword: "password", PasswordFile: filepath.Join(dir, "tenant-password")}},
+			}},
+			field:        func(c *Config) string { return c.Tenants[1].ArubaUser.ArubaPassword },
+			want:         "password",
+			wantProblems: []configProblem{{"tenants[1].arubaUser.passwordFile", "cannot be combined with tenants[1].arubaUser.password"}},
+		},
+		{
+			name:         "tenant unset env reference",
+			config:       Config{Tenants: []Tenant{{Name: "first", ArubaEndpoint: "${ENDPOINT}"}}},
+			field:        func(c *Config) string { return c.Tenants[0].ArubaEndpoint },
+			want:         "",
+			wantProblems: []configProblem{{"tenants[0].arubaEndpoint", "environment variable ENDPOINT is not set"}},
+		},
+		{
+			name:   "tenant not overridden by ARUBA_ variable",
+			config: Config{Tenants: []Tenant{{Name: "first", ArubaUser: UserCredentials{ArubaPassword: "tenant-password"}}}},
+			env:    map[string]string{"ARUBA_PASSWORD": "aruba-password"},
+			field:  func(c *Config) string { return c.Tenants[0].ArubaUser.ArubaPassword },
+			want:   "tenant-password",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			unsetEnv(t, "PASSWORD", "REGION", "SECRETS", "ENDPOINT")
+			for name := range (&Config{}).envOverrides() {
+				unsetEnv(t, name)
+			}
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+
+			c := test.config
+			problems := c.resolveSecrets()
+
+			if got := test.field(&c); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+			if !reflect.DeepEqual(problems, test.wantProblems) {
+				t.Errorf("got problems %v, want %v", problems, test.wantProblems)
+			}
+		})
+	}
+}
+
+// unsetEnv unsets the environment variables for the rest of the test.
+func unsetEnv(t *testing.T, names ...string) {
+	for _, name := range names {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+}
+
+func TestResolveSecretsTrimsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := ioutil.WriteFile(path, []byte("  token \n\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	unsetEnv(t, "ARUBA_RELOAD_TOKEN")
+
+	c := Config{ExporterConfig: ExporterConfig{ReloadTokenFile: path}}
+	if problems := c.resolveSecrets(); len(problems) > 0 {
+		t.Fatal(problems)
+	}
+	if c.ExporterConfig.ReloadToken != "token" {
+		t.Errorf("got reload token %q, want token", c.ExporterConfig.ReloadToken)
+	}
+}
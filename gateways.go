@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type Gateway struct {
+	CpuUtilization        int      `json:"cpu_utilization"`
+	FirmwareBackupVersion string   `json:"firmware_backup_version"`
+	FirmwareVersion       string   `json:"firmware_version"`
+	GroupName             string   `json:"group_name"`
+	IpAddress             string   `json:"ip_address"`
+	Labels                []string `json:"labels"`
+	MacAddress            string   `json:"macaddr"`
+	MemFree               int      `json:"mem_free"`
+	MemTotal              int      `json:"mem_total"`
+	Mode                  string   `json:"mode"`
+	Model                 string   `json:"model"`
+	Name                  string   `json:"name"`
+	RebootReason          string   `json:"reboot_reason"`
+	Role                  string   `json:"role"`
+	Serial                string   `json:"serial"`
+	Site                  string   `json:"site"`
+	Status                string   `json:"status"`
+	Uptime                int      `json:"uptime"`
+}
+
+type GatewayUplink struct {
+	LinkIndex  int    `json:"link_index"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	UplinkType string `json:"uplink_type"`
+	Vlan       int    `json:"vlan"`
+}
+
+type GatewayUplinkResponse struct {
+	Uplinks []GatewayUplink `json:"uplinks"`
+}
+
+type GatewayUplinkBandwidthResponse struct {
+	Samples []struct {
+		RxDataBytes int64 `json:"rx_data_bytes"`
+		TxDataBytes int64 `json:"tx_data_bytes"`
+		Timestamp   int64 `json:"timestamp"`
+	} `json:"samples"`
+}
+
+type GatewayTunnelResponse struct {
+	Tunnels []struct {
+		Name   string `json:"name"`
+		Status string `json:"status"`
+	} `json:"tunnels"`
+}
+
+var (
+	gatewayUp             = prometheus.NewDesc("aruba_gateway_up", "Whether the gateway is up", []string{"name", "serial"}, nil)
+	gatewayCpuUtilization = newDeviceDesc("aruba_gateway_cpu_utilization", "CPU Utilization of the gateway in percentage", []string{"name", "groupName", "model", "site", "status", "firmwareVersion"})
+	gatewayMemFree        = newDeviceDesc("aruba_gateway_mem_free", "Amount of free memory of gateway", []string{"name", "groupName", "model", "site", "status", "firmwareVersion"})
+	gatewayMemTotal       = newDeviceDesc("aruba_gateway_mem_total", "Total amount of memory of gateway", []string{"name", "groupName", "model", "site", "status", "firmwareVersion"})
+	gatewayUptime         = newDeviceDesc("aruba_gateway_uptime", "Uptime of the gateway in seconds", []string{"name", "groupName", "model", "site", "status", "firmwareVersion"})
+
+	gatewayUplinkUp     = prometheus.NewDesc("aruba_gateway_uplink_up", "Whether the gateway uplink is up", []string{"gatewayName", "serial", "site", "groupName", "uplink", "type"}, nil)
+	gatewayUplinkRxRate = prometheus.NewDesc("aruba_gateway_uplink_rx_bytes_per_second", "Data received on the gateway uplink in bytes per second over the latest sample", []string{"gatewayName", "serial", "site", "groupName", "uplink", "type"}, nil)
+	gatewayUplinkTxRate = prometheus.NewDesc("aruba_gateway_uplink_tx_bytes_per_second", "Data sent on the gateway uplink in bytes per second over the latest sample", []string{"gatewayName", "serial", "site", "groupName", "uplink", "type"}, nil)
+
+	gatewayTunnels = prometheus.NewDesc("aruba_gateway_tunnels", "Number of tunnels of the gateway by status", []string{"gatewayName", "serial", "site", "groupName", "status"}, nil)
+)
+
+// gatewayCollector fetches the uplinks, their bandwidth and the tunnels of
+// every gateway separately, so it costs 2 API calls per gateway plus 1 per
+// uplink and is disabled by default.
+type gatewayCollector struct{}
+
+func init() {
+	registerCollector("gateways", false, gatewayCollector{})
+}
+
+func (gatewayCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- gatewayUp
+	ch <- gatewayCpuUtilization.desc()
+	ch <- gatewayMemFree.desc()
+	ch <- gatewayMemTotal.desc()
+	ch <- gatewayUptime.desc()
+
+	ch <- gatewayUplinkUp
+	ch <- gatewayUplinkRxRate
+	ch <- gatewayUplinkTxRate
+
+	ch <- gatewayTunnels
+}
+
+func (gatewayCollector) Update(ctx context.Context, client *CentralClient, ch chan<- prometheus.Metric) error {
+
+	query := url.Values{
+		"calculate_total": {"true"},
+	}
+	client.scope.apply(query)
+
+	gateways, err := listAll[Gateway](ctx, client, "monitoring/v1/gateways", "gateways", query, 0)
+	if err != nil {
+		return fmt.Errorf("listing gateways: %w", err)
+	}
+
+	for _, g := range gateways {
+
+		ch <- prometheus.MustNewConstMetric(gatewayUp, prometheus.GaugeValue, boolValue(g.Status == "Up"), g.Name, g.Serial)
+		ch <- gatewayCpuUtilization.metric(float64(g.CpuUtilization), g.Name, g.GroupName, g.Model, g.Site, g.Status, g.FirmwareVersion)
+		ch <- gatewayMemFree.metric(float64(g.MemFree), g.Name, g.GroupName, g.Model, g.Site, g.Status, g.FirmwareVersion)
+		ch <- gatewayMemTotal.metric(float64(g.MemTotal), g.Name, g.GroupName, g.Model, g.Site, g.Status, g.FirmwareVersion)
+		ch <- gatewayUptime.metric(float64(g.Uptime), g.Name, g.GroupName, g.Model, g.Site, g.Status, g.FirmwareVersion)
+
+		// A gateway that is down cannot report its uplinks and tunnels.
+		if g.Status != "Up" {
+			continue
+		}
+
+		// One gateway failing to report its details should not cost the
+		// metrics of all the others.
+		if err := updateGatewayUplinks(ctx, client, g, ch); err != nil {
+			if detailsAbort(ctx, err) {
+				return err
+			}
+			fmt.Println(time.Now().Format(time.RFC3339), "Skipping gateway uplinks:", err)
+		}
+		if err := updateGatewayTunnels(ctx, client, g, ch); err != nil {
+			if detailsAbort(ctx, err) {
+				return err
+			}
+			fmt.Println(time.Now().Format(time.RFC3339), "Skipping gateway tunnels:", err)
+		}
+	}
+
+	return nil
+}
+
+func updateGatewayUplinks(ctx context.Context, client *CentralClient, g Gateway, ch chan<- prometheus.Metric) error {
+
+	var uplinkResponse GatewayUplinkResponse
+	if err := client.GetDevice(ctx, "monitoring/v1/gateways/{serial}/uplinks", g.Serial, nil, &uplinkResponse); err != nil {
+		return fmt.Errorf("listing uplinks of gateway %s: %w", g.Name, err)
+	}
+
+	for _, u := range uplinkResponse.Uplinks {
+
+		ch <- prometheus.MustNewConstMetric(gatewayUplinkUp, prometheus.GaugeValue, boolValue(u.Status == "Up"), g.Name, g.Serial, g.Site, g.GroupName, u.Name, u.UplinkType)
+
+		query := url.Values{
+			"uplink_id": {strconv.Itoa(u.LinkIndex)},
+		}
+
+		var bandwidthResponse GatewayUplinkBandwidthResponse
+		if err := client.GetDevice(ctx, "monitoring/v1/gateways/{serial}/uplinks/bandwidth_usage", g.Serial, query, &bandwidthResponse); err != nil {
+			return fmt.Errorf("getting bandwidth of uplink %s of gateway %s: %w", u.Name, g.Name, err)
+		}
+
+		// Each sample holds the bytes since the previous one.
+		samples := bandwidthResponse.Samples
+		if len(samples) < 2 {
+			continue
+		}
+		latest, previous := samples[len(samples)-1], samples[len(samples)-2]
+		if seconds := float64(latest.Timestamp - previous.Timestamp); seconds > 0 {
+			ch <- prometheus.MustNewConstMetric(gatewayUplinkRxRate, prometheus.GaugeValue, float64(latest.RxDataBytes)/seconds, g.Name, g.Serial, g.Site, g.GroupName, u.Name, u.UplinkType)
+			ch <- prometheus.MustNewConstMetric(gatewayUplinkTxRate, prometheus.GaugeValue, float64(latest.TxDataBytes)/seconds, g.Name, g.Serial, g.Site, g.GroupName, u.Name, u.UplinkType)
+		}
+	}
+
+	return nil
+}
+
+func updateGatewayTunnels(ctx context.Context, client *CentralClient, g Gateway, ch chan<- prometheus.Metric) error {
+
+	var tunnelResponse GatewayTunnelResponse
+	if err := client.GetDevice(ctx, "monitoring/v1/gateways/{serial}/tunnels", g.Serial, nil, &tunnelResponse); err != nil {
+		return fmt.Errorf("listing tunnels of gateway %s: %w", g.Name, err)
+	}
+
+	counts := map[string]int{}
+	for _, t := range tunnelResponse.Tunnels {
+		counts[t.Status]++
+	}
+
+	for status, count := range counts {
+		ch <- prometheus.MustNewConstMetric(gatewayTunnels, prometheus.GaugeValue, float64(count), g.Name, g.Serial, g.Site, g.GroupName, status)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type MobilityController struct {
+	CpuUtilization        int      `json:"cpu_utilization"`
+	FirmwareBackupVersion string   `json:"firmware_backup_version"`
+	FirmwareVersion       string   `json:"firmware_version"`
+	GroupName             string   `json:"group_name"`
+	IpAddress             string   `json:"ip_address"`
+	Labels                []string `json:"labels"`
+	MacRange              string   `json:"mac_range"`
+	MacAddress            string   `json:"macaddr"`
+	MemFree               int      `json:"mem_free"`
+	MemTotal              int      `json:"mem_total"`
+	Mode                  string   `json:"mode"`
+	Model                 string   `json:"model"`
+	Name                  string   `json:"name"`
+	RebootReason          string   `json:"reboot_reason"`
+	Role                  string   `json:"role"`
+	Serial                string   `json:"serial"`
+	Site                  string   `json:"site"`
+	Status                string   `json:"status"`
+	Uptime                int      `json:"uptime"`
+}
+
+var (
+	mcUp             = prometheus.NewDesc("aruba_mc_up", "Whether the mobility controller is up", []string{"name", "serial"}, nil)
+	mcCpuUtilization = newDeviceDesc("aruba_mc_cpu_utilization", "CPU Utilization of the mobility controller in percentge", []string{"name", "groupName", "mode", "model", "site", "status", "firmwareVersion"})
+	mcMemFree        = newDeviceDesc("aruba_mc_mem_free", "Amount of free memory of mobility controller", []string{"name", "groupName", "mode", "model", "site", "status", "firmwareVersion"})
+	mcMemTotal       = newDeviceDesc("aruba_mc_mem_total", "Total amount of  memory of mobility controller", []string{"name", "groupName", "mode", "model", "site", "status", "firmwareVersion"})
+	mcUptime         = newDeviceDesc("aruba_mc_uptime", "Uptime of the mobility controller in seconds", []string{"name", "groupName", "mode", "model", "site", "status", "firmwareVersion"})
+)
+
+type mcCollector struct{}
+
+func init() {
+	registerCollector("mobility_controllers", true, mcCollector{})
+}
+
+func (mcCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- mcUp
+	ch <- mcCpuUtilization.desc()
+	ch <- mcMemFree.desc()
+	ch <- mcMemTotal.desc()
+	ch <- mcUptime.desc()
+}
+
+func (mcCollector) Update(ctx context.Context, client *CentralClient, ch chan<- prometheus.Metric) error {
+
+	query := url.Values{
+		"calculate_total": {"true"},
+	}
+	client.scope.apply(query)
+
+	mobilityControllers, err := listAll[MobilityController](ctx, client, "monitoring/v1/mobility_controllers", "mcs", query, 0)
+	if err != nil {
+		return fmt.Errorf("listing mobility controllers: %w", err)
+	}
+
+	for _, m := range mobilityControllers {
+
+		ch <- prometheus.MustNewConstMetric(mcUp, prometheus.GaugeValue, boolValue(m.Status == "Up"), m.Name, m.Serial)
+		ch <- mcCpuUtilization.metric(float64(m.CpuUtilization), m.Name, m.GroupName, m.Mode, m.Model, m.Site, m.Status, m.FirmwareVersion)
+		ch <- mcMemFree.metric(float64(m.MemFree), m.Name, m.GroupName, m.Mode, m.Model, m.Site, m.Status, m.FirmwareVersion)
+		ch <- mcMemTotal.metric(float64(m.MemTotal), m.Name, m.GroupName, m.Mode, m.Model, m.Site, m.Status, m.FirmwareVersion)
+		ch <- mcUptime.metric(float64(m.Uptime), m.Name, m.GroupName, m.Mode, m.Model, m.Site, m.Status, m.FirmwareVersion)
+	}
+
+	return nil
+}
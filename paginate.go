@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+)
+
+// listPage is one page of a Central list endpoint.
+type listPage[T any] struct {
+	items []T
+	total int // -1 if the endpoint did not report it
+}
+
+// listAll pages through a Central list endpoint with offset and limit until
+// every item has been fetched. The items are read from the itemsKey field of
+// each response. maxPageSize caps the configured page size for endpoints that
+// allow less. Once the first page reports the total, the remaining pages are
+// fetched concurrently if parallelPages allows it.
+func listAll[T any](ctx context.Context, c *CentralClient, path string, itemsKey string, query url.Values, maxPageSize int) ([]T, error) {
+	limit := c.pagination.PageSize
+	if maxPageSize > 0 && limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	first, err := getPage[T](ctx, c, path, itemsKey, query, 0, limit)
+	if err != nil {
+		return nil, err
+	}
+	items := first.items
+
+	if first.total < 0 {
+		// Without a total, keep going until a page comes back short.
+		for page := first; len(page.items) == limit; {
+			page, err = getPage[T](ctx, c, path, itemsKey, query, len(items), limit)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, page.items...)
+		}
+		return items, nil
+	}
+
+	// A short first page of a longer list means Central caps the page size
+	// below the limit asked for, so step by what it actually returns.
+	if len(first.items) > 0 && len(first.items) < limit && len(first.items) < first.total {
+		limit = len(first.items)
+	}
+
+	var offsets []int
+	for offset := len(first.items); offset < first.total && len(first.items) > 0; offset += limit {
+		offsets = append(offsets, offset)
+	}
+
+	pages := make([][]T, len(offsets))
+	errs := make([]error, len(offsets))
+	workers := make(chan struct{}, c.pagination.ParallelPages)
+
+	// One failed page fails the whole list, so stop fetching the others.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i, offset := range offsets {
+		wg.Add(1)
+		workers <- struct{}{}
+		go func(i int, offset int) {
+			defer wg.Done()
+			defer func() { <-workers }()
+			page, err := getPage[T](ctx, c, path, itemsKey, query, offset, limit)
+			if err != nil {
+				cancel()
+			}
+			pages[i], errs[i] = page.items, err
+		}(i, offset)
+	}
+	wg.Wait()
+
+	// Report the error that caused the others to be cancelled.
+	var firstErr error
+	for _, err := range errs {
+		if err != nil && (firstErr == nil || errors.Is(firstErr, context.Canceled)) {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	for _, page := range pages {
+		items = append(items, page...)
+	}
+	return items, nil
+}
+
+func getPage[T any](ctx context.Context, c *CentralClient, path string, itemsKey string, query url.Values, offset int, limit int) (listPage[T], error) {
+	pageQuery := url.Values{}
+	for key, values := range query {
+		pageQuery[key] = values
+	}
+	pageQuery.Set("offset", strconv.Itoa(offset))
+	pageQuery.Set("limit", strconv.Itoa(limit))
+
+	var response map[string]json.RawMessage
+	if err := c.Get(ctx, path, pageQuery, &response); err != nil {
+		return listPage[T]{}, err
+	}
+
+	page := listPage[T]{total: -1}
+	if raw, ok := response[itemsKey]; ok {
+		if err := json.Unmarshal(raw, &page.items); err != nil {
+			return listPage[T]{}, fmt.Errorf("parsing %s from %s: %w", itemsKey, path, err)
+		}
+	}
+	if raw, ok := response["total"]; ok {
+		if err := json.Unmarshal(raw, &page.total); err != nil {
+			page.total = -1
+		}
+	}
+	return page, nil
+}
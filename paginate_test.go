@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestCentralClient returns a client with a valid access token talking to a
+// fake Central served by handler.
+func newTestCentralClient(t *testing.T, handler http.Handler, pagination PaginationConfig) *CentralClient {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	tokens := NewTokenManager(&Config{ArubaEndpoint: server.URL + "/"})
+	tokens.accessToken = "access"
+	tokens.refreshToken = "refresh"
+	tokens.expiry = time.Now().Add(time.Hour)
+
+	return NewCentralClient(server.URL+"/", tokens, newRateLimiter("", RateLimitConfig{}), pagination)
+}
+
+type testItem struct {
+	ID int `json:"id"`
+}
+
+// fakeList serves count items, at most maxPage at a time.
+type fakeList struct {
+	count     int
+	maxPage   int
+	withTotal bool
+	failAt    int // offset answered with 500, or -1
+	calls     int32
+}
+
+func (f *fakeList) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt32(&f.calls, 1)
+
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if r.URL.Query().Get("site") != "HQ" {
+		http.Error(w, "query not passed on", http.StatusBadRequest)
+		return
+	}
+	if offset == f.failAt {
+		http.Error(w, "failed", http.StatusInternalServerError)
+		return
+	}
+	if f.maxPage > 0 && limit > f.maxPage {
+		limit = f.maxPage
+	}
+
+	items := []testItem{}
+	for i := offset; i < offset+limit && i < f.count; i++ {
+		items = append(items, testItem{ID: i})
+	}
+
+	response := map[string]interface{}{"items": items}
+	if f.withTotal {
+		response["total"] = f.count
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func TestListAll(t *testing.T) {
+	tests := []struct {
+		name          string
+		count         int
+		maxPage       int
+		withTotal     bool
+		pageSize      int
+		maxPageSize   int
+		parallelPages int
+		wantCalls     int32
+	}{
+		{"single page with total", 3, 0, true, 5, 0, 1, 1},
+		{"several pages with total", 12, 0, true, 5, 0, 1, 3},
+		{"parallel pages with total", 12, 0, true, 5, 0, 3, 3},
+		{"exact pages with total", 10, 0, true, 5, 0, 2, 2},
+		{"empty with total", 0, 0, true, 5, 0, 1, 1},
+		{"single page without total", 3, 0, false, 5, 0, 1, 1},
+		{"several pages without total", 12, 0, false, 5, 0, 1, 3},
+		{"exact pages without total", 10, 0, false, 5, 0, 1, 3},
+		{"capped by maxPageSize", 12, 0, true, 100, 5, 2, 3},
+		{"short pages with total", 12, 3, true, 5, 0, 2, 4},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			list := &fakeList{count: test.count, maxPage: test.maxPage, withTotal: test.withTotal, failAt: -1}
+			client := newTestCentralClient(t, list, PaginationConfig{PageSize: test.pageSize, ParallelPages: test.parallelPages})
+
+			items, err := listAll[testItem](context.Background(), client, "list", "items", map[string][]string{"site": {"HQ"}}, test.maxPageSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := []testItem{}
+			for i := 0; i < test.count; i++ {
+				want = append(want, testItem{ID: i})
+			}
+			if len(items) != 0 || len(want) != 0 {
+				if !reflect.DeepEqual(items, want) {
+					t.Errorf("got %v, want %v", items, want)
+				}
+			}
+			if list.calls != test.wantCalls {
+				t.Errorf("got %d calls, want %d", list.calls, test.wantCalls)
+			}
+		})
+	}
+}
+
+func TestListAllFailingPage(t *testing.T) {
+	tests := []struct {
+		name          string
+		withTotal     bool
+		parallelPages int
+	}{
+		{"sequential with total", true, 1},
+		{"parallel with total", true, 4},
+		{"without total", false, 4},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			list := &fakeList{count: 50, withTotal: test.withTotal, failAt: 20}
+			client := newTestCentralClient(t, list, PaginationConfig{PageSize: 5, ParallelPages: test.parallelPages})
+
+			items, err := listAll[testItem](context.Background(), client, "list", "items", map[string][]string{"site": {"HQ"}}, 0)
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+				t.Fatalf("got error %v, want the failed page's", err)
+			}
+			if items != nil {
+				t.Errorf("got %d items from a failed list", len(items))
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	collectorLastSuccess = prometheus.NewDesc("aruba_collector_last_success_timestamp_seconds", "Time the collector last polled Central successfully", []string{"collector"}, nil)
+	collectorDataAge     = prometheus.NewDesc("aruba_collector_data_age_seconds", "Age of the cached data served for the collector", []string{"collector"}, nil)
+)
+
+type snapshot struct {
+	metrics   []prometheus.Metric
+	timestamp time.Time // of the last successful poll
+
+	// Outcome of the most recent poll.
+	polled   time.Time
+	success  bool
+	duration time.Duration
+}
+
+// Poller refreshes every collector in the background on its own interval and
+// serves scrapes from the last successful snapshot, so that scrapes never
+// call Central themselves.
+type Poller struct {
+	exporter *Exporter
+	config   PollingConfig
+
+	mu        sync.RWMutex
+	snapshots map[string]snapshot
+}
+
+func NewPoller(exporter *Exporter, config PollingConfig) *Poller {
+	return &Poller{
+		exporter:  exporter,
+		config:    config,
+		snapshots: map[string]snapshot{},
+	}
+}
+
+// inherit takes over the snapshots of old, which polled the same tenant before
+// a config reload, for the collectors that are still enabled. Scrapes are then
+// answered from them straight away, and each collector carries on polling on
+// its schedule rather than all of them calling Central at once. It must be
+// called before Run.
+func (p *Poller) inherit(old *Poller) {
+	old.mu.RLock()
+	defer old.mu.RUnlock()
+
+	for _, c := range p.exporter.collectors {
+		if s, ok := old.snapshots[c.name]; ok {
+			p.snapshots[c.name] = s
+		}
+	}
+}
+
+// Run polls every collector until ctx is cancelled.
+func (p *Poller) Run(ctx context.Context) {
+	for _, c := range p.exporter.collectors {
+		go p.poll(ctx, c.name, c.collector, p.config.interval(c.name))
+	}
+}
+
+func (p *Poller) poll(ctx context.Context, name string, collector Collector, interval time.Duration) {
+	p.mu.RLock()
+	next := p.snapshots[name].polled.Add(interval)
+	p.mu.RUnlock()
+
+	if wait := time.Until(next); wait > 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		p.update(ctx, name, collector, interval)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// update runs the collector once, replacing its cached metrics only if it
+// succeeded.
+func (p *Poller) update(ctx context.Context, name string, collector Collector, interval time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, interval)
+	defer cancel()
+
+	start := time.Now()
+
+	ch := make(chan prometheus.Metric)
+	done := make(chan error, 1)
+	go func() {
+		err := collector.Update(ctx, p.exporter.client, ch)
+		close(ch)
+		done <- err
+	}()
+
+	var metrics []prometheus.Metric
+	for m := range ch {
+		metrics = append(metrics, m)
+	}
+
+	err := <-done
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := p.snapshots[name]
+	s.polled = start
+	s.success = err == nil
+	s.duration = time.Since(start)
+	if err != nil {
+		fmt.Printf("Error %v\n", err)
+	} else {
+		s.metrics = metrics
+		s.timestamp = time.Now()
+	}
+	p.snapshots[name] = s
+}
+
+// Collect sends the cached metrics of each of the given collectors that has
+// succeeded at least once along with how old they are, and the outcome of its
+// last poll.
+func (p *Poller) Collect(ch chan<- prometheus.Metric, collectors []*namedCollector) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, c := range collectors {
+		s, ok := p.snapshots[c.name]
+		if !ok {
+			continue
+		}
+
+		success := 0.0
+		if s.success {
+			success = 1
+		}
+		ch <- prometheus.MustNewConstMetric(scrapeCollectorSuccess, prometheus.GaugeValue, success, c.name)
+		ch <- prometheus.MustNewConstMetric(scrapeCollectorDuration, prometheus.GaugeValue, s.duration.Seconds(), c.name)
+
+		if s.timestamp.IsZero() {
+			continue
+		}
+
+		for _, m := range s.metrics {
+			ch <- m
+		}
+
+		ch <- prometheus.MustNewConstMetric(collectorLastSuccess, prometheus.GaugeValue, float64(s.timestamp.UnixNano())/1e9, c.name)
+		ch <- prometheus.MustNewConstMetric(collectorDataAge, prometheus.GaugeValue, time.Since(s.timestamp).Seconds(), c.name)
+	}
+}
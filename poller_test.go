@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// countingCollector counts its updates and sends one metric each time.
+type countingCollector struct {
+	updates int32
+}
+
+var countingDesc = prometheus.NewDesc("aruba_test_updates", "Updates of the test collector", nil, nil)
+
+func (c *countingCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- countingDesc
+}
+
+func (c *countingCollector) Update(ctx context.Context, client *CentralClient, ch chan<- prometheus.Metric) error {
+	n := atomic.AddInt32(&c.updates, 1)
+	ch <- prometheus.MustNewConstMetric(countingDesc, prometheus.GaugeValue, float64(n))
+	return nil
+}
+
+func newTestPoller(collector Collector, interval time.Duration) *Poller {
+	exporter := &Exporter{collectors: []*namedCollector{{name: "test", collector: collector}}}
+	exporter.poller = NewPoller(exporter, PollingConfig{Enabled: true, Interval: interval})
+	return exporter.poller
+}
+
+func TestPollerInherit(t *testing.T) {
+	first := &countingCollector{}
+	old := newTestPoller(first, time.Hour)
+	old.update(context.Background(), "test", first, time.Hour)
+
+	// The old poller also knows a collector the reloaded config disabled.
+	old.snapshots["disabled"] = snapshot{timestamp: time.Now()}
+
+	second := &countingCollector{}
+	poller := newTestPoller(second, time.Hour)
+	poller.inherit(old)
+	if _, ok := poller.snapshots["disabled"]; ok {
+		t.Error("inherited the snapshot of a disabled collector")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	poller.Run(ctx)
+	time.Sleep(50 * time.Millisecond)
+
+	if updates := atomic.LoadInt32(&second.updates); updates != 0 {
+		t.Errorf("got %d updates right after the reload, want 0", updates)
+	}
+
+	ch := make(chan prometheus.Metric, 10)
+	poller.Collect(ch, poller.exporter.collectors)
+	close(ch)
+	var found bool
+	for m := range ch {
+		if m.Desc() == countingDesc {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("inherited metrics not served")
+	}
+}
+
+func TestPollerInheritOverdue(t *testing.T) {
+	first := &countingCollector{}
+	old := newTestPoller(first, time.Hour)
+	old.update(context.Background(), "test", first, time.Hour)
+
+	// Reloaded with an interval that has already passed since the last poll.
+	second := &countingCollector{}
+	poller := newTestPoller(second, time.Millisecond)
+	poller.inherit(old)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	poller.Run(ctx)
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&second.updates) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("overdue collector not polled after the reload")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
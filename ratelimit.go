@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ErrBudgetExhausted is returned instead of calling Central once the
+// configured daily API budget has been used up.
+var ErrBudgetExhausted = errors.New("daily API call budget exhausted")
+
+var (
+	apiRateLimitRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "aruba_exporter_api_rate_limit_remaining",
+		Help: "API calls remaining in the current period as last reported by Central",
+	}, []string{"tenant", "period"})
+	apiBudgetRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "aruba_exporter_api_budget_remaining",
+		Help: "API calls remaining today within the configured daily budget",
+	}, []string{"tenant"})
+	apiBudgetRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "aruba_exporter_api_budget_rejections_total",
+		Help: "Number of API calls skipped because the daily budget was exhausted",
+	}, []string{"tenant"})
+)
+
+// rateLimiter is a token bucket that spaces out calls to Central, with an
+// optional cap on the number of calls per UTC day. Central limits each
+// customer separately, so every tenant has its own.
+type rateLimiter struct {
+	tenant string // empty for the top-level credentials
+
+	mu          sync.Mutex
+	rate        float64
+	burst       float64
+	tokens      float64
+	last        time.Time
+	dailyBudget int
+	day         time.Time
+	used        int
+
+	// Set from a 429 response's Retry-After, no call is made before this.
+	blockedUntil time.Time
+}
+
+func newRateLimiter(tenant string, c RateLimitConfig) *rateLimiter {
+	l := &rateLimiter{tenant: tenant}
+	l.setLimits(c)
+	l.tokens = l.burst
+	return l
+}
+
+// setLimits applies new limits, keeping the calls already made today.
+func (l *rateLimiter) setLimits(c RateLimitConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate = c.RequestsPerSecond
+	l.burst = float64(c.Burst)
+	l.tokens = math.Min(l.tokens, l.burst)
+	l.dailyBudget = c.DailyBudget
+}
+
+// Wait blocks until a call may be made, or returns an error if ctx is done
+// first or the daily budget is exhausted.
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	for {
+		delay, err := l.reserve()
+		if err != nil || delay == 0 {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve takes a token if one is available, and otherwise returns how long
+// to wait before trying again.
+func (l *rateLimiter) reserve() (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	if today := now.UTC().Truncate(24 * time.Hour); !today.Equal(l.day) {
+		l.day = today
+		l.used = 0
+	}
+	if l.dailyBudget > 0 && l.used >= l.dailyBudget {
+		apiBudgetRejections.WithLabelValues(l.tenant).Inc()
+		return 0, ErrBudgetExhausted
+	}
+
+	if now.Before(l.blockedUntil) {
+		return l.blockedUntil.Sub(now), nil
+	}
+
+	if l.rate > 0 {
+		if !l.last.IsZero() {
+			l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+		}
+		l.last = now
+
+		if l.tokens < 1 {
+			return time.Duration((1 - l.tokens) / l.rate * float64(time.Second)), nil
+		}
+		l.tokens--
+	}
+
+	l.used++
+	if l.dailyBudget > 0 {
+		apiBudgetRemaining.WithLabelValues(l.tenant).Set(float64(l.dailyBudget - l.used))
+	}
+	return 0, nil
+}
+
+// block holds back all calls for d after Central reports being rate limited.
+func (l *rateLimiter) block(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(d); until.After(l.blockedUntil) {
+		l.blockedUntil = until
+	}
+}
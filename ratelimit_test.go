@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterDailyBudget(t *testing.T) {
+	tests := []struct {
+		name    string
+		day     time.Time
+		used    int
+		wantErr error
+	}{
+		{"within budget", time.Now().UTC().Truncate(24 * time.Hour), 2, nil},
+		{"exhausted", time.Now().UTC().Truncate(24 * time.Hour), 3, ErrBudgetExhausted},
+		{"exhausted yesterday", time.Now().UTC().Truncate(24 * time.Hour).Add(-24 * time.Hour), 3, nil},
+		{"first call", time.Time{}, 0, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := newRateLimiter("", RateLimitConfig{DailyBudget: 3})
+			l.day = test.day
+			l.used = test.used
+
+			delay, err := l.reserve()
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+			if delay != 0 {
+				t.Errorf("got delay %s, want none", delay)
+			}
+			if err != nil {
+				return
+			}
+
+			// A new day starts counting again from this call.
+			wantUsed := test.used + 1
+			if !test.day.Equal(time.Now().UTC().Truncate(24 * time.Hour)) {
+				wantUsed = 1
+			}
+			if l.used != wantUsed {
+				t.Errorf("got %d calls used, want %d", l.used, wantUsed)
+			}
+		})
+	}
+}
+
+func TestRateLimiterBlock(t *testing.T) {
+	l := newRateLimiter("", RateLimitConfig{RequestsPerSecond: 5, Burst: 5})
+	l.block(2 * time.Second)
+
+	delay, err := l.reserve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delay <= time.Second || delay > 2*time.Second {
+		t.Errorf("got delay %s, want about 2s", delay)
+	}
+
+	// A shorter Retry-After does not cut the block short.
+	l.block(time.Millisecond)
+	if delay, _ := l.reserve(); delay <= time.Second {
+		t.Errorf("got delay %s after a shorter block, want about 2s", delay)
+	}
+
+	// Wait gives up when the block outlasts the context.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want deadline exceeded", err)
+	}
+
+	// No call was counted while blocked.
+	if l.used != 0 {
+		t.Errorf("got %d calls used while blocked, want 0", l.used)
+	}
+
+	l.blockedUntil = time.Now().Add(-time.Millisecond)
+	if delay, err := l.reserve(); delay != 0 || err != nil {
+		t.Errorf("got delay %s and error %v after the block, want neither", delay, err)
+	}
+}
+
+func TestRateLimiterBurst(t *testing.T) {
+	l := newRateLimiter("", RateLimitConfig{RequestsPerSecond: 1, Burst: 2})
+
+	for i := 0; i < 2; i++ {
+		if delay, err := l.reserve(); delay != 0 || err != nil {
+			t.Fatalf("call %d: got delay %s and error %v, want neither", i, delay, err)
+		}
+	}
+	if delay, _ := l.reserve(); delay <= 0 || delay > time.Second {
+		t.Errorf("got delay %s after the burst, want up to 1s", delay)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	configReloadSuccessful = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "aruba_exporter_config_last_reload_successful",
+		Help: "Whether the last configuration reload attempt was successful",
+	})
+	configReloadTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "aruba_exporter_config_last_reload_success_timestamp_seconds",
+		Help: "Timestamp of the last successful configuration reload",
+	})
+)
+
+// Reloader holds the Exporters built from the current config and atomically
+// replaces them when the config file is reloaded.
+type Reloader struct {
+	configPath string
+
+	// Keyed by tenant name, with the top-level credentials under "".
+	exporters atomic.Pointer[map[string]*Exporter]
+
+	// Guarded by mu, which serialises reloads.
+	mu          sync.Mutex
+	config      *Config
+	stopTokens  map[string]context.CancelFunc
+	stopPolling context.CancelFunc
+}
+
+// NewReloader loads the config and logs in to Central, returning an error if
+// either fails.
+func NewReloader(configPath string) (*Reloader, error) {
+	config, err := loadConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reloader{configPath: configPath}
+	if err := r.apply(config); err != nil {
+		return nil, err
+	}
+
+	configReloadSuccessful.Set(1)
+	configReloadTimestamp.SetToCurrentTime()
+	return r, nil
+}
+
+// Config returns the config currently in use.
+func (r *Reloader) Config() *Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.config
+}
+
+// Exporter returns the Exporter for the top-level credentials of the config
+// currently in use, or nil if there are none.
+func (r *Reloader) Exporter() *Exporter {
+	return (*r.exporters.Load())[""]
+}
+
+// TenantExporter returns the Exporter for the named tenant.
+func (r *Reloader) TenantExporter(name string) (*Exporter, bool) {
+	if name == "" {
+		return nil, false
+	}
+	exporter, ok := (*r.exporters.Load())[name]
+	return exporter, ok
+}
+
+// Reload re-reads the config file and switches to it if it is valid, keeping
+// the running config otherwise.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.reload()
+	if err != nil {
+		configReloadSuccessful.Set(0)
+		fmt.Println(time.Now().Format(time.RFC3339), "Error reloading config, keeping the previous one:", err)
+		return err
+	}
+
+	configReloadSuccessful.Set(1)
+	configReloadTimestamp.SetToCurrentTime()
+	fmt.Println(time.Now().Format(time.RFC3339), "Config reloaded from", r.configPath)
+	return nil
+}
+
+func (r *Reloader) reload() error {
+	config, err := loadConfig(r.configPath)
+	if err != nil {
+		return err
+	}
+
+	if config.ExporterConfig.ExporterEndpoint != r.config.ExporterConfig.ExporterEndpoint || config.ExporterConfig.ExporterPort != r.config.ExporterConfig.ExporterPort {
+		fmt.Println(time.Now().Format(time.RFC3339), "Changes to exporterEndpoint and exporterPort only take effect after a restart")
+	}
+
+	return r.apply(config)
+}
+
+// apply builds an Exporter for every tenant in config and swaps them in. A
+// tenant whose credentials did not change keeps its tokens and rate limiter,
+// so a reload does not cost a login, while the others log in anew. If any
+// login fails, nothing is swapped. mu must be held.
+func (r *Reloader) apply(config *Config) error {
+	var previous map[string]*Config
+	if r.config != nil {
+		previous = r.config.tenantConfigs()
+	}
+	var old map[string]*Exporter
+	if loaded := r.exporters.Load(); loaded != nil {
+		old = *loaded
+	}
+
+	clients := map[string]*CentralClient{}
+	stopTokens := map[string]context.CancelFunc{}
+	reused := map[string]bool{}
+	var started []context.CancelFunc
+
+	for name, tenant := range config.tenantConfigs() {
+		if before, ok := previous[name]; ok && tenant.sameCredentials(before) {
+			client := old[name].client
+			client.limiter.setLimits(config.RateLimit)
+			clients[name] = NewCentralClient(tenant.ArubaEndpoint, client.tokens, client.limiter, config.Pagination)
+			stopTokens[name] = r.stopTokens[name]
+			reused[name] = true
+			continue
+		}
+
+		tokens := NewTokenManager(tenant)
+		if err := tokens.Start(context.Background()); err != nil {
+			for _, stop := range started {
+				stop()
+			}
+			if name == "" {
+				return fmt.Errorf("authenticating: %w", err)
+			}
+			return fmt.Errorf("authenticating tenant %s: %w", name, err)
+		}
+
+		ctx, stop := context.WithCancel(context.Background())
+		go tokens.Run(ctx)
+		started = append(started, stop)
+
+		clients[name] = NewCentralClient(tenant.ArubaEndpoint, tokens, newRateLimiter(name, config.RateLimit), config.Pagination)
+		stopTokens[name] = stop
+	}
+
+	// Stop refreshing the tokens that are being replaced.
+	for name, stop := range r.stopTokens {
+		if !reused[name] {
+			stop()
+		}
+	}
+
+	exporters := map[string]*Exporter{}
+	for name, client := range clients {
+		exporter := NewExporter(client, config)
+		// The tenant is unchanged, so keep serving what was polled so far.
+		if reused[name] && exporter.poller != nil && old[name].poller != nil {
+			exporter.poller.inherit(old[name].poller)
+		}
+		exporters[name] = exporter
+	}
+	r.exporters.Store(&exporters)
+	r.config = config
+	r.stopTokens = stopTokens
+
+	if r.stopPolling != nil {
+		r.stopPolling()
+	}
+	var ctx context.Context
+	ctx, r.stopPolling = context.WithCancel(context.Background())
+	for _, exporter := range exporters {
+		exporter.Start(ctx)
+	}
+
+	return nil
+}
+
+// WatchSIGHUP reloads the config whenever the process receives SIGHUP.
+func (r *Reloader) WatchSIGHUP() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		r.Reload()
+	}
+}
+
+// ServeHTTP handles POST /-/reload. It requires the reload token from the
+// config as a bearer token, and is disabled if none is configured.
+func (r *Reloader) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Only POST requests are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	reloadToken := r.Config().ExporterConfig.ReloadToken
+	if reloadToken == "" {
+		http.Error(w, "Reloading over HTTP is disabled, set exporterConfig.reloadToken to enable it", http.StatusForbidden)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(req.Header.Get("Authorization")), []byte("Bearer "+reloadToken)) != 1 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.Reload(); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to reload config: %s", err), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintln(w, "Config reloaded")
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type RadioStatsResponse struct {
+	ChannelStats []struct {
+		ChannelBusy         float64 `json:"channel_busy"`
+		ChannelInterference float64 `json:"channel_interference"`
+		NoiseFloor          float64 `json:"noise_floor"`
+		RxTime              float64 `json:"rx_time"`
+		Timestamp           int64   `json:"timestamp"`
+		TxTime              float64 `json:"tx_time"`
+	} `json:"channel_stats"`
+	FrameStats []struct {
+		RxErrors  int64 `json:"rx_errors"`
+		RxFrames  int64 `json:"rx_frames"`
+		Timestamp int64 `json:"timestamp"`
+		TxFrames  int64 `json:"tx_frames"`
+		TxRetries int64 `json:"tx_retries"`
+	} `json:"frame_stats"`
+}
+
+type AccessPointBssids struct {
+	Name        string `json:"name"`
+	Serial      string `json:"serial"`
+	RadioBssids []struct {
+		Index      int    `json:"index"`
+		MacAddress string `json:"macaddr"`
+		Bssids     []struct {
+			ClientCount int    `json:"client_count"`
+			Essid       string `json:"essid"`
+			MacAddress  string `json:"macaddr"`
+		} `json:"bssids"`
+	} `json:"radio_bssids"`
+}
+
+// Labels identifying a radio.
+var radioLabels = []string{"apName", "serial", "radio", "radioMac", "band", "channel", "radioType", "spatialStream", "status"}
+
+var (
+	radioTxPower            = prometheus.NewDesc("aruba_radio_tx_power_dbm", "Transmit power of the radio in dBm", radioLabels, nil)
+	radioNoiseFloor         = prometheus.NewDesc("aruba_radio_noise_floor_dbm", "Noise floor of the radio's channel in dBm", radioLabels, nil)
+	radioChannelBusy        = prometheus.NewDesc("aruba_radio_channel_busy_percent", "Percentage of time the radio's channel was busy", radioLabels, nil)
+	radioChannelUtilization = prometheus.NewDesc("aruba_radio_channel_utilization_percent", "Percentage of time the radio's channel was busy by source: tx, rx or interference", append(radioLabels[:len(radioLabels):len(radioLabels)], "source"), nil)
+	radioTxRetries          = prometheus.NewDesc("aruba_radio_tx_retries_percent", "Percentage of frames the radio had to retransmit", radioLabels, nil)
+	radioRxErrors           = prometheus.NewDesc("aruba_radio_rx_errors_percent", "Percentage of frames the radio received with errors", radioLabels, nil)
+
+	bssidClientCount = prometheus.NewDesc("aruba_bssid_client_count", "Number of clients connected to the BSSID", append(radioLabels[:len(radioLabels):len(radioLabels)], "bssid", "ssid"), nil)
+)
+
+// rfCollector fetches the RF statistics of every radio separately, so it
+// costs one API call per radio and is disabled by default.
+type rfCollector struct{}
+
+func init() {
+	registerCollector("rf", false, rfCollector{})
+}
+
+func (rfCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- radioTxPower
+	ch <- radioNoiseFloor
+	ch <- radioChannelBusy
+	ch <- radioChannelUtilization
+	ch <- radioTxRetries
+	ch <- radioRxErrors
+
+	ch <- bssidClientCount
+}
+
+func (rfCollector) Update(ctx context.Context, client *CentralClient, ch chan<- prometheus.Metric) error {
+
+	query := url.Values{}
+	client.scope.apply(query)
+
+	accessPoints, err := listAll[AccessPoint](ctx, client, "monitoring/v2/aps", "aps", query, 0)
+	if err != nil {
+		return fmt.Errorf("listing access points: %w", err)
+	}
+
+	// The label values of each radio by AP serial and radio index.
+	radios := map[string]map[int][]string{}
+
+	for _, a := range accessPoints {
+
+		radios[a.Serial] = map[int][]string{}
+
+		for _, r := range a.Radios {
+
+			labels := []string{a.Name, a.Serial, strconv.Itoa(r.Index), r.MacAddress, strconv.Itoa(r.Band), r.Channel, r.RadioType, r.SpatialStream, r.Status}
+			radios[a.Serial][r.Index] = labels
+
+			ch <- prometheus.MustNewConstMetric(radioTxPower, prometheus.GaugeValue, float64(r.TxPower), labels...)
+
+			// A radio that is down has no RF statistics.
+			if a.Status != "Up" || r.Status != "Up" {
+				continue
+			}
+			// One radio failing to report its statistics should not cost the
+			// metrics of all the others.
+			if err := updateRadioStats(ctx, client, a, r.Index, labels, ch); err != nil {
+				if detailsAbort(ctx, err) {
+					return err
+				}
+				fmt.Println(time.Now().Format(time.RFC3339), "Skipping radio statistics:", err)
+			}
+		}
+	}
+
+	bssids, err := listAll[AccessPointBssids](ctx, client, "monitoring/v2/bssids", "aps", query, 0)
+	if err != nil {
+		return fmt.Errorf("listing BSSIDs: %w", err)
+	}
+
+	for _, a := range bssids {
+		for _, r := range a.RadioBssids {
+
+			labels, ok := radios[a.Serial][r.Index]
+			if !ok {
+				continue
+			}
+
+			for _, b := range r.Bssids {
+				ch <- prometheus.MustNewConstMetric(bssidClientCount, prometheus.GaugeValue, float64(b.ClientCount), append(labels[:len(labels):len(labels)], b.MacAddress, b.Essid)...)
+			}
+		}
+	}
+
+	return nil
+}
+
+// updateRadioStats sends the latest RF statistics of one radio of a.
+func updateRadioStats(ctx context.Context, client *CentralClient, a AccessPoint, index int, labels []string, ch chan<- prometheus.Metric) error {
+
+	query := url.Values{
+		"radio_number": {strconv.Itoa(index)},
+	}
+
+	var statsResponse RadioStatsResponse
+	if err := client.GetDevice(ctx, "monitoring/v1/aps/{serial}/rf_summary", a.Serial, query, &statsResponse); err != nil {
+		return fmt.Errorf("getting RF statistics of radio %d of access point %s: %w", index, a.Name, err)
+	}
+
+	if n := len(statsResponse.ChannelStats); n > 0 {
+		latest := statsResponse.ChannelStats[n-1]
+		ch <- prometheus.MustNewConstMetric(radioNoiseFloor, prometheus.GaugeValue, latest.NoiseFloor, labels...)
+		ch <- prometheus.MustNewConstMetric(radioChannelBusy, prometheus.GaugeValue, latest.ChannelBusy, labels...)
+		ch <- prometheus.MustNewConstMetric(radioChannelUtilization, prometheus.GaugeValue, latest.TxTime, append(labels[:len(labels):len(labels)], "tx")...)
+		ch <- prometheus.MustNewConstMetric(radioChannelUtilization, prometheus.GaugeValue, latest.RxTime, append(labels[:len(labels):len(labels)], "rx")...)
+		ch <- prometheus.MustNewConstMetric(radioChannelUtilization, prometheus.GaugeValue, latest.ChannelInterference, append(labels[:len(labels):len(labels)], "interference")...)
+	}
+
+	if n := len(statsResponse.FrameStats); n > 0 {
+		latest := statsResponse.FrameStats[n-1]
+		if latest.TxFrames > 0 {
+			ch <- prometheus.MustNewConstMetric(radioTxRetries, prometheus.GaugeValue, 100*float64(latest.TxRetries)/float64(latest.TxFrames), labels...)
+		}
+		if latest.RxFrames > 0 {
+			ch <- prometheus.MustNewConstMetric(radioRxErrors, prometheus.GaugeValue, 100*float64(latest.RxErrors)/float64(latest.RxFrames), labels...)
+		}
+	}
+
+	return nil
+}
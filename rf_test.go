@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestRFSkipsFailingRadio(t *testing.T) {
+	tests := []struct {
+		name        string
+		dailyBudget int
+		wantErr     error
+		wantRadios  int
+		wantBssids  int
+	}{
+		{"failing radio skipped", 0, nil, 1, 2},
+		{"budget exhausted", 2, ErrBudgetExhausted, 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/monitoring/v2/aps", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"aps":[{"name":"ap","serial":"A","status":"Up","radios":[{"index":0,"status":"Up"},{"index":1,"status":"Up"}]}],"total":1}`))
+			})
+			mux.HandleFunc("/monitoring/v1/aps/A/rf_summary", func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("radio_number") == "0" {
+					http.Error(w, "failed", http.StatusInternalServerError)
+					return
+				}
+				w.Write([]byte(`{"channel_stats":[{"noise_floor":-95}]}`))
+			})
+			mux.HandleFunc("/monitoring/v2/bssids", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"aps":[{"serial":"A","radio_bssids":[{"index":0,"bssids":[{"essid":"corp","client_count":3}]},{"index":1,"bssids":[{"essid":"corp","client_count":5}]}]}],"total":1}`))
+			})
+			client := newTestCentralClient(t, mux, PaginationConfig{PageSize: 100, ParallelPages: 1})
+			client.limiter = newRateLimiter("", RateLimitConfig{DailyBudget: test.dailyBudget})
+
+			ch := make(chan prometheus.Metric, 100)
+			err := rfCollector{}.Update(context.Background(), client, ch)
+			close(ch)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+
+			var radios, bssids int
+			for m := range ch {
+				switch m.Desc() {
+				case radioNoiseFloor:
+					radios++
+				case bssidClientCount:
+					bssids++
+				}
+			}
+			if radios != test.wantRadios {
+				t.Errorf("got noise floor of %d radios, want %d", radios, test.wantRadios)
+			}
+			if bssids != test.wantBssids {
+				t.Errorf("got %d BSSIDs, want %d", bssids, test.wantBssids)
+			}
+		})
+	}
+}
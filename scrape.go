@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Time left out of Prometheus' scrape timeout for encoding and sending the
+// response.
+const scrapeTimeoutOffset = 500 * time.Millisecond
+
+// scrapeCollector runs an Exporter's collectors under the context of one
+// scrape request.
+type scrapeCollector struct {
+	ctx        context.Context
+	exporter   *Exporter
+	collectors []*namedCollector
+}
+
+func (s scrapeCollector) Describe(ch chan<- *prometheus.Desc) {
+	s.exporter.Describe(ch)
+}
+
+func (s scrapeCollector) Collect(ch chan<- prometheus.Metric) {
+	s.exporter.collect(s.ctx, ch, s.collectors)
+}
+
+// metricsHandler serves the exporter's own metrics together with those
+// collected from Central for the top-level credentials, if there are any.
+func metricsHandler(reloader *Reloader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		serveScrape(w, req, reloader.Exporter(), prometheus.DefaultGatherer)
+	})
+}
+
+// probeHandler serves the metrics collected from Central for the tenant
+// named in the tenant parameter, or the top-level credentials without one.
+// With a target parameter, only that site or group is scraped, using the
+// collectors and target type of the module parameter.
+func probeHandler(reloader *Reloader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		params := req.URL.Query()
+		tenant := params.Get("tenant")
+		target := params.Get("target")
+
+		if tenant == "" && target == "" {
+			http.Error(w, "Tenant or target parameter is missing", http.StatusBadRequest)
+			return
+		}
+
+		exporter := reloader.Exporter()
+		if tenant != "" {
+			var ok bool
+			if exporter, ok = reloader.TenantExporter(tenant); !ok {
+				http.Error(w, fmt.Sprintf("Unknown tenant %q", tenant), http.StatusBadRequest)
+				return
+			}
+		} else if exporter == nil {
+			http.Error(w, "Tenant parameter is missing and no top-level credentials are configured", http.StatusBadRequest)
+			return
+		}
+
+		if target != "" {
+			module := ModuleConfig{TargetType: "site"}
+			if name := params.Get("module"); name != "" {
+				var ok bool
+				if module, ok = reloader.Config().Modules[name]; !ok {
+					http.Error(w, fmt.Sprintf("Unknown module %q", name), http.StatusBadRequest)
+					return
+				}
+			}
+
+			scope := Scope{Site: target}
+			if module.TargetType == "group" {
+				scope = Scope{Group: target}
+			}
+			exporter = exporter.scoped(scope)
+
+			if len(module.Collectors) > 0 {
+				collectors, err := exporter.filter(module.Collectors)
+				if err != nil {
+					http.Error(w, fmt.Sprintf("Invalid module %q: %s", params.Get("module"), err), http.StatusBadRequest)
+					return
+				}
+				exporter.collectors = collectors
+			}
+		}
+
+		serveScrape(w, req, exporter)
+	})
+}
+
+// serveScrape runs the exporter's collectors, giving them a deadline derived
+// from the X-Prometheus-Scrape-Timeout-Seconds header, and serves their
+// metrics together with those of the other gatherers. Only the collectors
+// named in collect[] parameters are run if there are any.
+func serveScrape(w http.ResponseWriter, req *http.Request, exporter *Exporter, others ...prometheus.Gatherer) {
+	registry := prometheus.NewRegistry()
+
+	if exporter != nil {
+		collectors := exporter.collectors
+		if names := req.URL.Query()["collect[]"]; len(names) > 0 {
+			var err error
+			collectors, err = exporter.filter(names)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid collect[] parameter: %s", err), http.StatusBadRequest)
+				return
+			}
+		}
+
+		ctx := req.Context()
+		if timeout, ok := scrapeTimeout(req); ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		registry.MustRegister(scrapeCollector{ctx: ctx, exporter: exporter, collectors: collectors})
+	}
+
+	// Gather from Central first so the API request metrics include the calls
+	// made for this scrape.
+	gatherers := append(prometheus.Gatherers{registry}, others...)
+	promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{}).ServeHTTP(w, req)
+}
+
+func scrapeTimeout(req *http.Request) (time.Duration, bool) {
+	seconds, err := strconv.ParseFloat(req.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"), 64)
+	if err != nil || seconds <= 0 {
+		return 0, false
+	}
+
+	timeout := time.Duration(seconds * float64(time.Second))
+	if timeout > 2*scrapeTimeoutOffset {
+		timeout -= scrapeTimeoutOffset
+	}
+	return timeout, true
+}
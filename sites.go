@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type Site struct {
+	BranchCpuHigh          int     `json:"branch_cpu_high"`
+	BranchDeviceStatusDown int     `json:"branch_device_status_down"`
+	BranchDeviceStatusUp   int     `json:"branch_device_status_up"`
+	BranchMemHigh          int     `json:"branch_mem_high"`
+	CapeState              string  `json:"cape_state"`
+	CapeStateDesc          string  `json:"cape_state_dscr"`
+	CapeStateUrl           string  `json:"cape_url"`
+	ConnectedCount         int     `json:"connected_count"`
+	DeviceDown             int     `json:"device_down"`
+	DeviceHighCh24         int     `json:"device_high_ch_2_4ghz"`
+	DeviceHighCh5          int     `json:"device_high_ch_5ghz"`
+	DeviceHighCpu          int     `json:"device_high_cpu"`
+	DeviceHighMem          int     `json:"device_high_mem"`
+	DeviceHighNoise24      int     `json:"device_high_noise_2_4ghz"`
+	DeviceHighNoise5       int     `json:"device_high_noise_5ghz"`
+	DeviceUp               int     `json:"device_up"`
+	FailedCount            int     `json:"failed_count"`
+	Id                     string  `json:"id"`
+	InsightHi              int     `json:"insight_hi"`
+	InsightLo              int     `json:"insight_lo"`
+	InsightMi              int     `json:"insight_mi"`
+	Lat                    float64 `json:"lat"`
+	Long                   float64 `json:"long"`
+	Name                   string  `json:"name"`
+	PotentialIssue         bool    `json:"potential_issue"`
+	Score                  int     `json:"score"`
+	SilverPeakState        string  `json:"silverpeak_state"`
+	SilverPeakStateSummary string  `json:"silverpeak_state_summary"`
+	SilverPeakUrl          string  `json:"silverpeak_url"`
+	UserConnHealthScore    int     `json:"user_conn_health_score"`
+	WanTunnelsDown         int     `json:"wan_tunnels_down"`
+	WanTunnelsNoIssue      int     `json:"wan_tunnels_no_issue"`
+	WanUplinksDown         int     `json:"wan_uplinks_down"`
+	WanUplinksNoIssue      int     `json:"wan_uplinks_no_issue"`
+	WiredCPUHigh           int     `json:"wired_cpu_high"`
+	WiredDeviceSatusDown   int     `json:"wired_device_status_down"`
+	WiredDeviceStatusUp    int     `json:"wired_device_status_up"`
+	WiredMemHigh           int     `json:"wired_mem_high"`
+	WlanCpuHigh            int     `json:"wlan_cpu_high"`
+	WlanDeviceStatusDown   int     `json:"wlan_device_status_down"`
+	WlanDeviceStatusUp     int     `json:"wlan_device_status_up"`
+	WlanMemHigh            int     `json:"wlan_mem_high"`
+}
+
+var (
+	siteConnectedCount        = prometheus.NewDesc("aruba_site_connected_count", "Number of connected devices", []string{"name", "id"}, nil)
+	siteDeviceDown            = prometheus.NewDesc("aruba_site_device_down", "Number of down devices", []string{"name", "id"}, nil)
+	siteDeviceHighCh24        = prometheus.NewDesc("aruba_site_device_high_ch_2_4ghz", "Number of devices with high 2.4ghz channel utilization", []string{"name", "id"}, nil)
+	siteDeviceHighCh5         = prometheus.NewDesc("aruba_site_device_high_ch_5ghz", "Number of devices with high 5ghz channel utilization", []string{"name", "id"}, nil)
+	siteDeviceHighCpu         = prometheus.NewDesc("aruba_site_device_high_cpu", "Number of devices with high cpu utilization", []string{"name", "id"}, nil)
+	siteDeviceHighMem         = prometheus.NewDesc("aruba_site_device_high_mem", "Number of devices with high mem utilization", []string{"name", "id"}, nil)
+	siteDeviceHighNoise24     = prometheus.NewDesc("aruba_site_device_high_noise_2_4ghz", "Number of devices with high 2.4ghz noise", []string{"name", "id"}, nil)
+	siteDeviceHighNoise5      = prometheus.NewDesc("aruba_site_device_high_noise_5ghz", "Number of devices with high 5ghz noise", []string{"name", "id"}, nil)
+	siteDeviceUp              = prometheus.NewDesc("aruba_site_device_up", "Number of up devices", []string{"name", "id"}, nil)
+	siteWiredCpuHigh          = prometheus.NewDesc("aruba_site_wired_cpu_high", "Number of wired devices with high CPU", []string{"name", "id"}, nil)
+	siteWiredDeviceStatusDown = prometheus.NewDesc("aruba_site_wired_device_status_down", "Number of wired devices up", []string{"name", "id"}, nil)
+	siteWiredDeviceStatusUp   = prometheus.NewDesc("aruba_site_wired_device_status_up", "Number of wired devices down", []string{"name", "id"}, nil)
+	siteWiredMemHigh          = prometheus.NewDesc("aruba_site_wired_mem_high", "Number of wired devices with high memory usage", []string{"name", "id"}, nil)
+	siteWlanCpuHigh           = prometheus.NewDesc("aruba_site_wlan_cpu_high", "Number of wired devices with high cpu usage", []string{"name", "id"}, nil)
+	siteWlanDeviceStatusDown  = prometheus.NewDesc("aruba_site_wlan_device_status_down", "Number of down wireless devices", []string{"name", "id"}, nil)
+	siteWlanDeviceStatusUp    = prometheus.NewDesc("aruba_site_wlan_device_status_up", "Number of down wireless devices", []string{"name", "id"}, nil)
+	siteWlanMemHigh           = prometheus.NewDesc("aruba_site_wlan_mem_high", "Number of wireless devices with high cpu", []string{"name", "id"}, nil)
+)
+
+type siteCollector struct{}
+
+func init() {
+	registerCollector("sites", true, siteCollector{})
+}
+
+func (siteCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- siteConnectedCount
+	ch <- siteDeviceDown
+	ch <- siteDeviceHighCh24
+	ch <- siteDeviceHighCh5
+	ch <- siteDeviceHighCpu
+	ch <- siteDeviceHighMem
+	ch <- siteDeviceHighNoise24
+	ch <- siteDeviceHighNoise5
+	ch <- siteDeviceUp
+	ch <- siteWiredCpuHigh
+	ch <- siteWiredDeviceStatusDown
+	ch <- siteWiredDeviceStatusUp
+	ch <- siteWiredMemHigh
+	ch <- siteWlanCpuHigh
+	ch <- siteWlanDeviceStatusDown
+	ch <- siteWlanDeviceStatusUp
+	ch <- siteWlanMemHigh
+}
+
+func (siteCollector) Update(ctx context.Context, client *CentralClient, ch chan<- prometheus.Metric) error {
+
+	// Branch health has no group filter and reports nothing per group, so
+	// group targets get no site metrics.
+	if client.scope.Group != "" {
+		return nil
+	}
+
+	query := url.Values{
+		"column": {"device_total"},
+		"order":  {"desc"},
+	}
+
+	// Branch health returns at most 100 sites per request. It has no site
+	// filter either, so a site target still pages through every site.
+	sites, err := listAll[Site](ctx, client, "branchhealth/v1/site", "items", query, 100)
+	if err != nil {
+		return fmt.Errorf("listing sites: %w", err)
+	}
+
+	for _, s := range sites {
+		if client.scope.Site != "" && s.Name != client.scope.Site {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(siteConnectedCount, prometheus.GaugeValue, float64(s.ConnectedCount), s.Name, s.Id)
+		ch <- prometheus.MustNewConstMetric(siteDeviceDown, prometheus.GaugeValue, float64(s.DeviceDown), s.Name, s.Id)
+		ch <- prometheus.MustNewConstMetric(siteDeviceHighCh24, prometheus.GaugeValue, float64(s.DeviceHighCh24), s.Name, s.Id)
+		ch <- prometheus.MustNewConstMetric(siteDeviceHighCh5, prometheus.GaugeValue, float64(s.DeviceHighCh5), s.Name, s.Id)
+		ch <- prometheus.MustNewConstMetric(siteDeviceHighCpu, prometheus.GaugeValue, float64(s.DeviceHighCpu), s.Name, s.Id)
+		ch <- prometheus.MustNewConstMetric(siteDeviceHighMem, prometheus.GaugeValue, float64(s.DeviceHighMem), s.Name, s.Id)
+		ch <- prometheus.MustNewConstMetric(siteDeviceHighNoise24, prometheus.GaugeValue, float64(s.DeviceHighNoise24), s.Name, s.Id)
+		ch <- prometheus.MustNewConstMetric(siteDeviceHighNoise5, prometheus.GaugeValue, float64(s.DeviceHighNoise5), s.Name, s.Id)
+		ch <- prometheus.MustNewConstMetric(siteDeviceUp, prometheus.GaugeValue, float64(s.DeviceUp), s.Name, s.Id)
+		ch <- prometheus.MustNewConstMetric(siteWiredCpuHigh, prometheus.GaugeValue, float64(s.WiredCPUHigh), s.Name, s.Id)
+		ch <- prometheus.MustNewConstMetric(siteWiredDeviceStatusDown, prometheus.GaugeValue, float64(s.WiredDeviceSatusDown), s.Name, s.Id)
+		ch <- prometheus.MustNewConstMetric(siteWiredDeviceStatusUp, prometheus.GaugeValue, float64(s.WiredDeviceStatusUp), s.Name, s.Id)
+		ch <- prometheus.MustNewConstMetric(siteWiredMemHigh, prometheus.GaugeValue, float64(s.WiredMemHigh), s.Name, s.Id)
+		ch <- prometheus.MustNewConstMetric(siteWlanCpuHigh, prometheus.GaugeValue, float64(s.WiredCPUHigh), s.Name, s.Id)
+		ch <- prometheus.MustNewConstMetric(siteWlanDeviceStatusDown, prometheus.GaugeValue, float64(s.WlanDeviceStatusDown), s.Name, s.Id)
+		ch <- prometheus.MustNewConstMetric(siteWlanDeviceStatusUp, prometheus.GaugeValue, float64(s.WlanDeviceStatusDown), s.Name, s.Id)
+		ch <- prometheus.MustNewConstMetric(siteWlanMemHigh, prometheus.GaugeValue, float64(s.WlanMemHigh), s.Name, s.Id)
+
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type SwitchPort struct {
+	AdminState       string  `json:"admin_state"`
+	DuplexMode       string  `json:"duplex_mode"`
+	InErrors         int64   `json:"in_errors"`
+	Mode             string  `json:"mode"`
+	Name             string  `json:"name"`
+	OutErrors        int64   `json:"out_errors"`
+	PoeClass         int     `json:"poe_class"`
+	PortNumber       string  `json:"port_number"`
+	PowerConsumption float64 `json:"power_consumption"`
+	RxUsage          int64   `json:"rx_usage"`
+	Speed            int     `json:"speed"` // in Mbit/s
+	Status           string  `json:"status"`
+	TxUsage          int64   `json:"tx_usage"`
+	Vlan             int     `json:"vlan"`
+}
+
+type SwitchPortResponse struct {
+	Ports []SwitchPort `json:"ports"`
+}
+
+var (
+	switchPortInfo           = prometheus.NewDesc("aruba_switch_port_info", "Duplex and VLAN mode of the switch port", []string{"name", "serial", "port", "duplex", "vlanMode"}, nil)
+	switchPortAdminUp        = prometheus.NewDesc("aruba_switch_port_admin_up", "Whether the switch port is administratively enabled", []string{"name", "serial", "port"}, nil)
+	switchPortUp             = prometheus.NewDesc("aruba_switch_port_up", "Whether the switch port is operationally up", []string{"name", "serial", "port"}, nil)
+	switchPortSpeed          = prometheus.NewDesc("aruba_switch_port_speed_bytes", "Speed of the switch port in bytes per second", []string{"name", "serial", "port"}, nil)
+	switchPortReceiveBytes   = prometheus.NewDesc("aruba_switch_port_receive_bytes_total", "Bytes received on the switch port", []string{"name", "serial", "port"}, nil)
+	switchPortTransmitBytes  = prometheus.NewDesc("aruba_switch_port_transmit_bytes_total", "Bytes sent on the switch port", []string{"name", "serial", "port"}, nil)
+	switchPortReceiveErrors  = prometheus.NewDesc("aruba_switch_port_receive_errors_total", "Receive errors on the switch port", []string{"name", "serial", "port"}, nil)
+	switchPortTransmitErrors = prometheus.NewDesc("aruba_switch_port_transmit_errors_total", "Transmit errors on the switch port", []string{"name", "serial", "port"}, nil)
+	switchPortPoeClass       = prometheus.NewDesc("aruba_switch_port_poe_class", "PoE class of the device powered by the switch port", []string{"name", "serial", "port"}, nil)
+	switchPortPoePower       = prometheus.NewDesc("aruba_switch_port_poe_power_watts", "Power drawn through the switch port in watts", []string{"name", "serial", "port"}, nil)
+)
+
+// switchPortCollector fetches the ports of every switch separately, so it
+// costs one API call per switch and is disabled by default.
+type switchPortCollector struct{}
+
+func init() {
+	registerCollector("switch_ports", false, switchPortCollector{})
+}
+
+func (switchPortCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- switchPortInfo
+	ch <- switchPortAdminUp
+	ch <- switchPortUp
+	ch <- switchPortSpeed
+	ch <- switchPortReceiveBytes
+	ch <- switchPortTransmitBytes
+	ch <- switchPortReceiveErrors
+	ch <- switchPortTransmitErrors
+	ch <- switchPortPoeClass
+	ch <- switchPortPoePower
+}
+
+func (switchPortCollector) Update(ctx context.Context, client *CentralClient, ch chan<- prometheus.Metric) error {
+
+	query := url.Values{}
+	client.scope.apply(query)
+
+	switches, err := listAll[Switch](ctx, client, "monitoring/v1/switches", "switches", query, 0)
+	if err != nil {
+		return fmt.Errorf("listing switches: %w", err)
+	}
+
+	for _, s := range switches {
+
+		// A switch that is down cannot report its ports.
+		if s.Status != "Up" {
+			continue
+		}
+
+		endpoint := "monitoring/v1/switches/{serial}/ports"
+		if s.SwitchType == "AOS-CX" {
+			endpoint = "monitoring/v1/cx_switches/{serial}/ports"
+		}
+
+		// One switch failing to report its ports should not cost the metrics
+		// of all the others.
+		var portResponse SwitchPortResponse
+		if err := client.GetDevice(ctx, endpoint, s.Serial, nil, &portResponse); err != nil {
+			err = fmt.Errorf("listing ports of switch %s: %w", s.Name, err)
+			if detailsAbort(ctx, err) {
+				return err
+			}
+			fmt.Println(time.Now().Format(time.RFC3339), "Skipping switch ports:", err)
+			continue
+		}
+
+		for _, p := range portResponse.Ports {
+
+			port := p.PortNumber
+			if port == "" {
+				port = p.Name
+			}
+
+			ch <- prometheus.MustNewConstMetric(switchPortInfo, prometheus.GaugeValue, 1, s.Name, s.Serial, port, p.DuplexMode, p.Mode)
+			ch <- prometheus.MustNewConstMetric(switchPortAdminUp, prometheus.GaugeValue, boolValue(p.AdminState == "Up"), s.Name, s.Serial, port)
+			ch <- prometheus.MustNewConstMetric(switchPortUp, prometheus.GaugeValue, boolValue(p.Status == "Up"), s.Name, s.Serial, port)
+			ch <- prometheus.MustNewConstMetric(switchPortSpeed, prometheus.GaugeValue, float64(p.Speed)*1e6/8, s.Name, s.Serial, port)
+			ch <- prometheus.MustNewConstMetric(switchPortReceiveBytes, prometheus.CounterValue, float64(p.RxUsage), s.Name, s.Serial, port)
+			ch <- prometheus.MustNewConstMetric(switchPortTransmitBytes, prometheus.CounterValue, float64(p.TxUsage), s.Name, s.Serial, port)
+			ch <- prometheus.MustNewConstMetric(switchPortReceiveErrors, prometheus.CounterValue, float64(p.InErrors), s.Name, s.Serial, port)
+			ch <- prometheus.MustNewConstMetric(switchPortTransmitErrors, prometheus.CounterValue, float64(p.OutErrors), s.Name, s.Serial, port)
+			ch <- prometheus.MustNewConstMetric(switchPortPoeClass, prometheus.GaugeValue, float64(p.PoeClass), s.Name, s.Serial, port)
+			ch <- prometheus.MustNewConstMetric(switchPortPoePower, prometheus.GaugeValue, p.PowerConsumption, s.Name, s.Serial, port)
+		}
+	}
+
+	return nil
+}
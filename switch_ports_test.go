@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestSwitchPortsSkipsFailingSwitch(t *testing.T) {
+	tests := []struct {
+		name        string
+		dailyBudget int
+		wantErr     error
+		wantPorts   int
+	}{
+		{"failing switch skipped", 0, nil, 1},
+		{"budget exhausted", 2, ErrBudgetExhausted, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/monitoring/v1/switches", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"switches":[{"name":"broken","serial":"A","status":"Up"},{"name":"working","serial":"B","status":"Up"}],"total":2}`))
+			})
+			mux.HandleFunc("/monitoring/v1/switches/A/ports", func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "failed", http.StatusInternalServerError)
+			})
+			mux.HandleFunc("/monitoring/v1/switches/B/ports", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"ports":[{"port_number":"1/1/1"}]}`))
+			})
+			client := newTestCentralClient(t, mux, PaginationConfig{PageSize: 100, ParallelPages: 1})
+			client.limiter = newRateLimiter("", RateLimitConfig{DailyBudget: test.dailyBudget})
+
+			ch := make(chan prometheus.Metric, 100)
+			err := switchPortCollector{}.Update(context.Background(), client, ch)
+			close(ch)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+
+			var ports int
+			for m := range ch {
+				if m.Desc() == switchPortInfo {
+					ports++
+				}
+			}
+			if ports != test.wantPorts {
+				t.Errorf("got %d ports, want %d", ports, test.wantPorts)
+			}
+		})
+	}
+}
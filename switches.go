@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type Switch struct {
+	ClientCount      int      `json:"client_count"`
+	CPUUtilization   int      `json:"cpu_utilization"`
+	FanSpeed         string   `json:"fan_speed"`
+	FirmwareVersion  string   `json:"firmware_version"`
+	GroupID          int      `json:"group_id"`
+	GroupName        string   `json:"group_name"`
+	IPAddress        string   `json:"ip_address"`
+	LabelIDs         []int    `json:"label_ids"`
+	Labels           []string `json:"labels"`
+	MacAddress       string   `json:"macaddr"`
+	MaxPower         int      `json:"max_power"`
+	MemFree          int      `json:"mem_free"`
+	MemTotal         int      `json:"mem_total"`
+	Model            string   `json:"model"`
+	Name             string   `json:"name"`
+	PoeConsumption   string   `json:"poe_consumption"`
+	PowerConsumption int      `json:"power_consumption"`
+	PublicIPAddress  string   `json:"public_ip_address"`
+	Serial           string   `json:"serial"`
+	Site             string   `json:"site"`
+	SiteID           int      `json:"site_id"`
+	StackID          string   `json:"stack_id"`
+	StackMemberID    int      `json:"stack_member_id"`
+	Status           string   `json:"status"`
+	SwitchRole       int      `json:"switch_role"`
+	SwitchType       string   `json:"switch_type"`
+	Temperature      string   `json:"temperature"`
+	UplinkPorts      []struct {
+		Port string `json:"port"`
+	} `json:"uplink_ports"`
+	Uptime int `json:"uptime"`
+	Usage  int `json:"usage"`
+}
+
+var (
+	switchUp             = prometheus.NewDesc("aruba_switch_up", "Whether the switch is up", []string{"name", "serial"}, nil)
+	switchClientCount    = newDeviceDesc("aruba_switch_client_count", "Number of clients connected to switch", []string{"name", "stackMemberId", "groupId", "groupName", "site", "siteId", "switchRole", "switchType", "status", "firmwareVersion", "model"})
+	switchCpuUtilization = newDeviceDesc("aruba_switch_cpu_utilization", "Current Switch CPU utilization percentage", []string{"name", "stackMemberId", "groupId", "groupName", "site", "siteId", "switchRole", "switchType", "status", "firmwareVersion", "model"})
+	switchMemFree        = newDeviceDesc("aruba_switch_mem_free", "Switch free memory", []string{"name", "stackMemberId", "groupId", "groupName", "site", "siteId", "switchRole", "switchType", "status", "firmwareVersion", "model"})
+	switchMemTotal       = newDeviceDesc("aruba_switch_mem_total", "Switch total memory", []string{"name", "stackMemberId", "groupId", "groupName", "site", "siteId", "switchRole", "switchType", "status", "firmwareVersion", "model"})
+	switchUsage          = newDeviceDesc("aruba_switch_usage", "Switch uptime", []string{"name", "stackMemberId", "groupId", "groupName", "site", "siteId", "switchRole", "switchType", "status", "firmwareVersion", "model"})
+	switchUptime         = newDeviceDesc("aruba_switch_uptime", "Switch usage", []string{"name", "stackMemberId", "groupId", "groupName", "site", "siteId", "switchRole", "switchType", "status", "firmwareVersion", "model"})
+)
+
+type switchCollector struct{}
+
+func init() {
+	registerCollector("switches", true, switchCollector{})
+}
+
+func (switchCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- switchUp
+	ch <- switchClientCount.desc()
+	ch <- switchCpuUtilization.desc()
+	ch <- switchMemFree.desc()
+	ch <- switchMemTotal.desc()
+	ch <- switchUsage.desc()
+	ch <- switchUptime.desc()
+}
+
+func (switchCollector) Update(ctx context.Context, client *CentralClient, ch chan<- prometheus.Metric) error {
+
+	query := url.Values{
+		"calculate_total":        {"true"},
+		"show_resource_details":  {"true"},
+		"calculate_client_count": {"true"},
+	}
+	client.scope.apply(query)
+
+	switches, err := listAll[Switch](ctx, client, "monitoring/v1/switches", "switches", query, 0)
+	if err != nil {
+		return fmt.Errorf("listing switches: %w", err)
+	}
+
+	for _, s := range switches {
+
+		ch <- prometheus.MustNewConstMetric(switchUp, prometheus.GaugeValue, boolValue(s.Status == "Up"), s.Name, s.Serial)
+		ch <- switchClientCount.metric(float64(s.ClientCount), s.Name, strconv.Itoa(s.StackMemberID), strconv.Itoa(s.GroupID), s.GroupName, s.Site, strconv.Itoa(s.SiteID), strconv.Itoa(s.SwitchRole), s.SwitchType, s.Status, s.FirmwareVersion, s.Model)
+		ch <- switchCpuUtilization.metric(float64(s.CPUUtilization), s.Name, strconv.Itoa(s.StackMemberID), strconv.Itoa(s.GroupID), s.GroupName, s.Site, strconv.Itoa(s.SiteID), strconv.Itoa(s.SwitchRole), s.SwitchType, s.Status, s.FirmwareVersion, s.Model)
+		ch <- switchMemFree.metric(float64(s.ClientCount), s.Name, strconv.Itoa(s.StackMemberID), strconv.Itoa(s.GroupID), s.GroupName, s.Site, strconv.Itoa(s.SiteID), strconv.Itoa(s.SwitchRole), s.SwitchType, s.Status, s.FirmwareVersion, s.Model)
+		ch <- switchMemTotal.metric(float64(s.ClientCount), s.Name, strconv.Itoa(s.StackMemberID), strconv.Itoa(s.GroupID), s.GroupName, s.Site, strconv.Itoa(s.SiteID), strconv.Itoa(s.SwitchRole), s.SwitchType, s.Status, s.FirmwareVersion, s.Model)
+		ch <- switchUsage.metric(float64(s.Usage), s.Name, strconv.Itoa(s.StackMemberID), strconv.Itoa(s.GroupID), s.GroupName, s.Site, strconv.Itoa(s.SiteID), strconv.Itoa(s.SwitchRole), s.SwitchType, s.Status, s.FirmwareVersion, s.Model)
+		ch <- switchUptime.metric(float64(s.Uptime), s.Name, strconv.Itoa(s.StackMemberID), strconv.Itoa(s.GroupID), s.GroupName, s.Site, strconv.Itoa(s.SiteID), strconv.Itoa(s.SwitchRole), s.SwitchType, s.Status, s.FirmwareVersion, s.Model)
+	}
+
+	return nil
+}
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Refresh the access token this long before Central expires it.
+const tokenRefreshMargin = 5 * time.Minute
+
+// Delay before retrying a failed background refresh.
+const tokenRetryInterval = 30 * time.Second
+
+// Bounds of the exponential backoff between failed re-authentications.
+const (
+	reauthMinBackoff = 30 * time.Second
+	reauthMaxBackoff = 30 * time.Minute
+)
+
+// errRefreshRejected is returned when Central refuses the refresh token, which
+// happens once it has expired or been revoked.
+var errRefreshRejected = errors.New("refresh token rejected")
+
+// refreshThrottledError is returned when Central answers a refresh with 429 or
+// 408. The refresh token is still good and is retried after RetryAfter.
+type refreshThrottledError struct {
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *refreshThrottledError) Error() string {
+	return fmt.Sprintf("token refresh throttled with status code %d, retrying in %s", e.StatusCode, e.RetryAfter)
+}
+
+var reauthentications = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "aruba_exporter_reauthentications_total",
+	Help: "Number of full logins performed after the refresh token was rejected",
+}, []string{"result"})
+
+// TokenManager owns the OAuth access and refresh tokens for a Central tenant
+// and keeps them fresh. It is safe for concurrent use by the collectors.
+type TokenManager struct {
+	config       *Config
+	endpoint     string
+	clientId     string
+	clientSecret string
+	customerId   string
+	cacheFile    string
+	client       *http.Client
+
+	// refreshMu serialises refreshes so concurrent callers only rotate the
+	// refresh token once.
+	refreshMu sync.Mutex
+
+	// Guarded by refreshMu.
+	reauthBackoff time.Duration
+	nextReauth    time.Time
+	nextRefresh   time.Time
+
+	mu           sync.RWMutex
+	accessToken  string
+	refreshToken string
+	expiry       time.Time
+}
+
+func NewTokenManager(c *Config) *TokenManager {
+	return &TokenManager{
+		config:       c,
+		endpoint:     c.ArubaEndpoint,
+		clientId:     c.ArubaApplicationCredentials.ClientID,
+		clientSecret: c.ArubaApplicationCredentials.ClientSecret,
+		customerId:   c.ArubaApplicationCredentials.CustomerID,
+		cacheFile:    c.TokenCacheFile,
+		client:       &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Start obtains the initial tokens. It prefers the token cache, then any
+// pre-issued tokens from the config, and only logs in with the user
+// credentials when neither is accepted by Central.
+func (t *TokenManager) Start(ctx context.Context) error {
+	if t.cacheFile != "" {
+		err := t.loadCache(ctx)
+		if err == nil {
+			return nil
+		}
+		if !os.IsNotExist(err) {
+			fmt.Println(time.Now().Format(time.RFC3339), "Not using token cache:", err)
+		}
+	}
+
+	if tokens := t.config.ArubaTokens; tokens.RefreshToken != "" {
+		// The lifetime of pre-issued tokens is unknown, so rotate them straight
+		// away.
+		err := t.exchange(ctx, tokens.AccessToken, tokens.RefreshToken)
+		if err == nil {
+			return nil
+		}
+		if !t.config.hasUserCredentials() {
+			return fmt.Errorf("using configured tokens: %w", err)
+		}
+		fmt.Println(time.Now().Format(time.RFC3339), "Not using configured tokens:", err)
+	}
+
+	return t.Login(ctx)
+}
+
+func (t *TokenManager) loadCache(ctx context.Context) error {
+	cached, err := readTokenCache(t.cacheFile)
+	if err != nil {
+		return err
+	}
+	if cached.Endpoint != t.endpoint || cached.ClientID != t.clientId || cached.CustomerID != t.customerId {
+		return errTokenCacheMismatch
+	}
+
+	t.mu.Lock()
+	t.accessToken = cached.AccessToken
+	t.refreshToken = cached.RefreshToken
+	t.expiry = cached.Expiry
+	t.mu.Unlock()
+
+	if time.Until(cached.Expiry) > tokenRefreshMargin {
+		return nil
+	}
+	return t.exchange(ctx, cached.AccessToken, cached.RefreshToken)
+}
+
+// Login performs the full user/password login and stores the resulting
+// tokens.
+func (t *TokenManager) Login(ctx context.Context) error {
+	response, err := authenticate(ctx, t.config)
+	if err != nil {
+		return err
+	}
+	t.store(response.AccessToken, response.RefreshToken, response.ExpiresIn)
+	return nil
+}
+
+// Token returns a valid access token, refreshing it first if it is about to
+// expire.
+func (t *TokenManager) Token(ctx context.Context) (string, error) {
+	accessToken, expiry := t.current()
+	if accessToken != "" && time.Until(expiry) > tokenRefreshMargin {
+		return accessToken, nil
+	}
+
+	if err := t.refresh(ctx, ""); err != nil {
+		// The old token may still be usable for a little while.
+		if accessToken != "" && time.Now().Before(expiry) {
+			return accessToken, nil
+		}
+		return "", err
+	}
+
+	accessToken, _ = t.current()
+	return accessToken, nil
+}
+
+// Expiry returns the time at which the current access token expires.
+func (t *TokenManager) Expiry() time.Time {
+	_, expiry := t.current()
+	return expiry
+}
+
+// Run refreshes the token in the background shortly before it expires until
+// ctx is cancelled.
+func (t *TokenManager) Run(ctx context.Context) {
+	for {
+		wait := time.Until(t.Expiry()) - tokenRefreshMargin
+		if wait < 0 {
+			wait = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		if err := t.refresh(ctx, ""); err != nil {
+			fmt.Println(time.Now().Format(time.RFC3339), "Error refreshing access token:", err)
+
+			retry := tokenRetryInterval
+			var throttled *refreshThrottledError
+			if errors.As(err, &throttled) && throttled.RetryAfter > retry {
+				retry = throttled.RetryAfter
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retry):
+			}
+		}
+	}
+}
+
+func (t *TokenManager) current() (string, time.Time) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.accessToken, t.expiry
+}
+
+// store saves a newly issued token pair and writes it back to the token
+// cache.
+func (t *TokenManager) store(accessToken string, refreshToken string, expiresIn int) {
+	expiry := time.Now().Add(time.Duration(expiresIn) * time.Second)
+
+	t.mu.Lock()
+	t.accessToken = accessToken
+	t.refreshToken = refreshToken
+	t.expiry = expiry
+	t.mu.Unlock()
+
+	if t.cacheFile == "" {
+		return
+	}
+
+	cached := &cachedTokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		Expiry:       expiry,
+		Endpoint:     t.endpoint,
+		ClientID:     t.clientId,
+		CustomerID:   t.customerId,
+	}
+	if err := writeTokenCache(t.cacheFile, cached); err != nil {
+		fmt.Println(time.Now().Format(time.RFC3339), "Error writing token cache:", err)
+	}
+}
+
+// Invalidate refreshes the tokens after Central refused the access token
+// rejected, for example because another process rotated it. Callers that were
+// refused the same token share a single refresh.
+func (t *TokenManager) Invalidate(ctx context.Context, rejected string) error {
+	return t.refresh(ctx, rejected)
+}
+
+// refresh exchanges the refresh token for a new token pair, falling back to a
+// full login if Central rejects it. It does nothing if the access token is not
+// about to expire, unless it is the rejected one, so a caller that waited for
+// another one's refresh does not rotate the tokens again.
+func (t *TokenManager) refresh(ctx context.Context, rejected string) error {
+	t.refreshMu.Lock()
+	defer t.refreshMu.Unlock()
+
+	t.mu.RLock()
+	accessToken := t.accessToken
+	refreshToken := t.refreshToken
+	expiry := t.expiry
+	t.mu.RUnlock()
+
+	if accessToken != "" && accessToken != rejected && time.Until(expiry) > tokenRefreshMargin {
+		return nil
+	}
+
+	if wait := time.Until(t.nextRefresh); wait > 0 {
+		return &refreshThrottledError{StatusCode: http.StatusTooManyRequests, RetryAfter: wait}
+	}
+
+	err := t.exchange(ctx, accessToken, refreshToken)
+
+	var throttled *refreshThrottledError
+	if errors.As(err, &throttled) {
+		t.nextRefresh = time.Now().Add(throttled.RetryAfter)
+		return err
+	}
+	if !errors.Is(err, errRefreshRejected) {
+		return err
+	}
+
+	fmt.Println(time.Now().Format(time.RFC3339), "Refresh token rejected, logging in again:", err)
+	return t.reauthenticate(ctx)
+}
+
+// reauthenticate runs the full login, backing off exponentially after
+// failures so a broken password does not hammer Central. refreshMu must be
+// held.
+func (t *TokenManager) reauthenticate(ctx context.Context) error {
+	if time.Now().Before(t.nextReauth) {
+		return fmt.Errorf("re-authentication backing off until %s", t.nextReauth.Format(time.RFC3339))
+	}
+
+	response, err := authenticate(ctx, t.config)
+	if err != nil {
+		reauthentications.WithLabelValues("failure").Inc()
+
+		if t.reauthBackoff == 0 {
+			t.reauthBackoff = reauthMinBackoff
+		} else if t.reauthBackoff *= 2; t.reauthBackoff > reauthMaxBackoff {
+			t.reauthBackoff = reauthMaxBackoff
+		}
+		t.nextReauth = time.Now().Add(t.reauthBackoff)
+
+		return fmt.Errorf("re-authenticating: %w", err)
+	}
+
+	reauthentications.WithLabelValues("success").Inc()
+	t.reauthBackoff = 0
+	t.nextReauth = time.Time{}
+
+	t.store(response.AccessToken, response.RefreshToken, response.ExpiresIn)
+	return nil
+}
+
+// exchange trades the refresh token for a new token pair.
+func (t *TokenManager) exchange(ctx context.Context, accessToken string, refreshToken string) error {
+	query := url.Values{}
+	query.Set("client_id", t.clientId)
+	query.Set("client_secret", t.clientSecret)
+	query.Set("grant_type", "refresh_token")
+	query.Set("refresh_token", refreshToken)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", t.endpoint+"oauth2/token?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response body: %w", err)
+	}
+
+	if verbose {
+		fmt.Println("\noauth2/token - HTTP Status Code:", resp.StatusCode)
+	}
+
+	// Only 400, 401 and 403 mean the refresh token is no good. Throttling and
+	// timeouts are retried later, as is anything else unexpected, rather than
+	// spending a full login on them.
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%w: status code %d", errRefreshRejected, resp.StatusCode)
+	case http.StatusTooManyRequests, http.StatusRequestTimeout:
+		retryAfter := defaultRetryAfter
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		return &refreshThrottledError{StatusCode: resp.StatusCode, RetryAfter: retryAfter}
+	default:
+		return fmt.Errorf("token refresh failed with status code: %d", resp.StatusCode)
+	}
+
+	var tokenResponse TokenResponse
+	if err := json.Unmarshal(body, &tokenResponse); err != nil {
+		return fmt.Errorf("parsing JSON: %w", err)
+	}
+
+	if tokenResponse.AccessToken == "" || tokenResponse.RefreshToken == "" {
+		return fmt.Errorf("%w: empty token in response", errRefreshRejected)
+	}
+
+	t.store(tokenResponse.AccessToken, tokenResponse.RefreshToken, tokenResponse.ExpiresIn)
+	return nil
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestTokenManager returns a TokenManager talking to a fake Central whose
+// oauth2/token endpoint is handled by handler.
+func newTestTokenManager(t *testing.T, handler http.HandlerFunc) *TokenManager {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return NewTokenManager(&Config{ArubaEndpoint: server.URL + "/"})
+}
+
+// tokenHandler answers every refresh with a new token pair numbered after the
+// call and counts the calls.
+func tokenHandler(calls *int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(calls, 1)
+		if r.URL.Path != "/oauth2/token" || r.URL.Query().Get("grant_type") != "refresh_token" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(TokenResponse{
+			AccessToken:  "access-" + strconv.Itoa(int(n)),
+			RefreshToken: "refresh-" + strconv.Itoa(int(n)),
+			ExpiresIn:    7200,
+		})
+	}
+}
+
+func TestTokenRefresh(t *testing.T) {
+	tests := []struct {
+		name        string
+		expiresIn   time.Duration
+		wantToken   string
+		wantRefresh int32
+	}{
+		{"valid", time.Hour, "access-old", 0},
+		{"inside margin", tokenRefreshMargin - time.Minute, "access-1", 1},
+		{"expired", -time.Minute, "access-1", 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var calls int32
+			tokens := newTestTokenManager(t, tokenHandler(&calls))
+			tokens.accessToken = "access-old"
+			tokens.refreshToken = "refresh-old"
+			tokens.expiry = time.Now().Add(test.expiresIn)
+
+			token, err := tokens.Token(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token != test.wantToken {
+				t.Errorf("got token %q, want %q", token, test.wantToken)
+			}
+			if calls != test.wantRefresh {
+				t.Errorf("got %d refreshes, want %d", calls, test.wantRefresh)
+			}
+		})
+	}
+}
+
+func TestTokenConcurrentRefresh(t *testing.T) {
+	var calls int32
+	tokens := newTestTokenManager(t, func(w http.ResponseWriter, r *http.Request) {
+		// Hold the refresh long enough for every caller to be waiting on it.
+		time.Sleep(50 * time.Millisecond)
+		tokenHandler(&calls)(w, r)
+	})
+	tokens.accessToken = "access-old"
+	tokens.refreshToken = "refresh-old"
+	tokens.expiry = time.Now().Add(time.Minute)
+
+	var wg sync.WaitGroup
+	results := make([]string, 20)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			token, err := tokens.Token(context.Background())
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = token
+		}(i)
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("refresh token rotated %d times, want 1", calls)
+	}
+	for i, token := range results {
+		if token != "access-1" {
+			t.Errorf("caller %d got token %q, want access-1", i, token)
+		}
+	}
+}
+
+func TestExchange(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		body         string
+		wantErr      bool
+		wantRejected bool
+	}{
+		{"ok", http.StatusOK, `{"access_token":"access-new","refresh_token":"refresh-new","expires_in":7200}`, false, false},
+		{"empty access token", http.StatusOK, `{"access_token":"","refresh_token":"refresh-new","expires_in":7200}`, true, true},
+		{"empty refresh token", http.StatusOK, `{"access_token":"access-new","refresh_token":"","expires_in":7200}`, true, true},
+		{"server error", http.StatusInternalServerError, `{}`, true, false},
+		{"bad gateway", http.StatusBadGateway, `{}`, true, false},
+		{"rejected", http.StatusBadRequest, `{"error":"invalid_grant"}`, true, true},
+		{"unauthorized", http.StatusUnauthorized, `{}`, true, true},
+		{"forbidden", http.StatusForbidden, `{}`, true, true},
+		{"rate limited", http.StatusTooManyRequests, `{}`, true, false},
+		{"timeout", http.StatusRequestTimeout, `{}`, true, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tokens := newTestTokenManager(t, func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Authorization"); got != "Bearer access-old" {
+					t.Errorf("got Authorization %q", got)
+				}
+				if got := r.URL.Query().Get("refresh_token"); got != "refresh-old" {
+					t.Errorf("got refresh_token %q", got)
+				}
+				w.WriteHeader(test.status)
+				w.Write([]byte(test.body))
+			})
+
+			err := tokens.exchange(context.Background(), "access-old", "refresh-old")
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if errors.Is(err, errRefreshRejected) != test.wantRejected {
+				t.Errorf("got error %v, want rejected %v", err, test.wantRejected)
+			}
+
+			accessToken, expiry := tokens.current()
+			if test.wantErr {
+				if accessToken != "" {
+					t.Errorf("stored token %q after a failed exchange", accessToken)
+				}
+				return
+			}
+			if accessToken != "access-new" || tokens.refreshToken != "refresh-new" {
+				t.Errorf("stored tokens %q and %q", accessToken, tokens.refreshToken)
+			}
+			if until := time.Until(expiry); until < 7100*time.Second || until > 7200*time.Second {
+				t.Errorf("got expiry in %s, want about 2h", until)
+			}
+		})
+	}
+}
+
+func TestRefreshThrottled(t *testing.T) {
+	var calls int32
+	tokens := newTestTokenManager(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	tokens.accessToken = "access-old"
+	tokens.refreshToken = "refresh-old"
+	tokens.expiry = time.Now().Add(time.Minute)
+
+	for i := 0; i < 3; i++ {
+		err := tokens.refresh(context.Background(), "")
+
+		var throttled *refreshThrottledError
+		if !errors.As(err, &throttled) {
+			t.Fatalf("got error %v, want throttled", err)
+		}
+		if throttled.RetryAfter <= 59*time.Second || throttled.RetryAfter > 60*time.Second {
+			t.Errorf("got retry after %s, want 60s", throttled.RetryAfter)
+		}
+	}
+
+	// The later refreshes wait for Retry-After instead of calling Central, and
+	// none of them falls back to a login.
+	if calls != 1 {
+		t.Errorf("got %d refreshes, want 1", calls)
+	}
+	if reauth := tokens.nextReauth; !reauth.IsZero() {
+		t.Errorf("re-authentication attempted")
+	}
+
+	// The old token stays in use while it is valid.
+	token, err := tokens.Token(context.Background())
+	if err != nil || token != "access-old" {
+		t.Errorf("got token %q and error %v, want access-old", token, err)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	var calls int32
+	tokens := newTestTokenManager(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		tokenHandler(&calls)(w, r)
+	})
+	tokens.accessToken = "access-old"
+	tokens.refreshToken = "refresh-old"
+	tokens.expiry = time.Now().Add(time.Hour)
+
+	// Every caller that was refused the old token shares one refresh.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := tokens.Invalidate(context.Background(), "access-old"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("refresh token rotated %d times, want 1", calls)
+	}
+	if token, _ := tokens.current(); token != "access-1" {
+		t.Errorf("got token %q, want access-1", token)
+	}
+
+	// A token that was already replaced is not refreshed again.
+	if err := tokens.Invalidate(context.Background(), "access-old"); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Errorf("stale invalidation rotated the tokens, got %d refreshes", calls)
+	}
+}
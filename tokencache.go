@@ -0,0 +1,129 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Environment variable holding the passphrase used to encrypt the token cache.
+// The cache is stored as plain JSON when it is unset.
+const tokenCacheKeyEnv = "ARUBA_TOKEN_CACHE_KEY"
+
+type cachedTokens struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	Expiry       time.Time `json:"expiry"`
+
+	// The credentials the tokens were issued for, so that a cache left behind
+	// by another tenant or customer is not used.
+	Endpoint   string `json:"endpoint"`
+	ClientID   string `json:"client_id"`
+	CustomerID string `json:"customer_id"`
+}
+
+// errTokenCacheMismatch is returned when the token cache was written for other
+// credentials than the ones configured.
+var errTokenCacheMismatch = errors.New("token cache was written for other credentials")
+
+func tokenCacheCipher() (cipher.AEAD, error) {
+	passphrase := os.Getenv(tokenCacheKeyEnv)
+	if passphrase == "" {
+		return nil, nil
+	}
+
+	key := sha256.Sum256([]byte(passphrase))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func readTokenCache(path string) (*cachedTokens, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := tokenCacheCipher()
+	if err != nil {
+		return nil, err
+	}
+
+	if aead != nil {
+		sealed, err := base64.StdEncoding.DecodeString(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("decoding token cache: %w", err)
+		}
+		if len(sealed) < aead.NonceSize() {
+			return nil, errors.New("token cache is truncated")
+		}
+		nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+		data, err = aead.Open(nil, nonce, ciphertext, nil)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting token cache: %w", err)
+		}
+	}
+
+	var tokens cachedTokens
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("parsing token cache: %w", err)
+	}
+	if tokens.RefreshToken == "" {
+		return nil, errors.New("token cache has no refresh token")
+	}
+	return &tokens, nil
+}
+
+// writeTokenCache atomically replaces the cache file, readable only by the
+// exporter's user.
+func writeTokenCache(path string, tokens *cachedTokens) error {
+	data, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+
+	aead, err := tokenCacheCipher()
+	if err != nil {
+		return err
+	}
+
+	if aead != nil {
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return err
+		}
+		sealed := aead.Seal(nonce, nonce, data, nil)
+		data = []byte(base64.StdEncoding.EncodeToString(sealed))
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
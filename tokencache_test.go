@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testCachedTokens() *cachedTokens {
+	return &cachedTokens{
+		AccessToken:  "access",
+		RefreshToken: "refresh",
+		Expiry:       time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC),
+		Endpoint:     "https://central.example.com/",
+		ClientID:     "client",
+		CustomerID:   "customer",
+	}
+}
+
+func TestTokenCacheRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{"plain", ""},
+		{"encrypted", "passphrase"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv(tokenCacheKeyEnv, test.key)
+			path := filepath.Join(t.TempDir(), "tokens.json")
+
+			if err := writeTokenCache(path, testCachedTokens()); err != nil {
+				t.Fatal(err)
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mode := info.Mode().Perm(); mode != 0600 {
+				t.Errorf("got mode %o, want 600", mode)
+			}
+
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if encrypted := !strings.Contains(string(data), "refresh"); encrypted != (test.key != "") {
+				t.Errorf("got cache %q, want encrypted %v", data, test.key != "")
+			}
+
+			tokens, err := readTokenCache(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *tokens != *testCachedTokens() {
+				t.Errorf("got %+v, want %+v", tokens, testCachedTokens())
+			}
+		})
+	}
+}
+
+func TestTokenCacheErrors(t *testing.T) {
+	encrypted := func(t *testing.T) string {
+		t.Setenv(tokenCacheKeyEnv, "passphrase")
+		path := filepath.Join(t.TempDir(), "tokens.json")
+		if err := writeTokenCache(path, testCachedTokens()); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	file := func(t *testing.T, data string) string {
+		path := filepath.Join(t.TempDir(), "tokens.json")
+		if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		path    func(t *testing.T) string
+		key     string
+		wantErr string
+	}{
+		{"wrong key", encrypted, "other passphrase", "decrypting token cache"},
+		{"encrypted without key", encrypted, "", "parsing token cache"},
+		{"plain with key", func(t *testing.T) string {
+			return file(t, `{"access_token":"access","refresh_token":"refresh"}`)
+		}, "passphrase", "decoding token cache"},
+		{"truncated", func(t *testing.T) string {
+			return file(t, base64.StdEncoding.EncodeToString([]byte("short")))
+		}, "passphrase", "token cache is truncated"},
+		{"no refresh token", func(t *testing.T) string {
+			return file(t, `{"access_token":"access"}`)
+		}, "", "token cache has no refresh token"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := test.path(t)
+			t.Setenv(tokenCacheKeyEnv, test.key)
+
+			_, err := readTokenCache(path)
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("got error %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+
+	t.Run("missing", func(t *testing.T) {
+		if _, err := readTokenCache(filepath.Join(t.TempDir(), "tokens.json")); !os.IsNotExist(err) {
+			t.Errorf("got error %v, want not exist", err)
+		}
+	})
+}
+
+func TestLoadCacheCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(c *Config)
+		wantErr error
+	}{
+		{"same credentials", func(c *Config) {}, nil},
+		{"other endpoint", func(c *Config) { c.ArubaEndpoint = "https://other.example.com/" }, errTokenCacheMismatch},
+		{"other client", func(c *Config) { c.ArubaApplicationCredentials.ClientID = "other" }, errTokenCacheMismatch},
+		{"other customer", func(c *Config) { c.ArubaApplicationCredentials.CustomerID = "other" }, errTokenCacheMismatch},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv(tokenCacheKeyEnv, "")
+
+			cached := testCachedTokens()
+			cached.Expiry = time.Now().Add(time.Hour)
+			path := filepath.Join(t.TempDir(), "tokens.json")
+			if err := writeTokenCache(path, cached); err != nil {
+				t.Fatal(err)
+			}
+
+			config := &Config{
+				ArubaEndpoint:               cached.Endpoint,
+				ArubaApplicationCredentials: ApplicationCredentials{ClientID: cached.ClientID, CustomerID: cached.CustomerID},
+				TokenCacheFile:              path,
+			}
+			test.change(config)
+
+			tokens := NewTokenManager(config)
+			err := tokens.loadCache(context.Background())
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+
+			want := "access"
+			if err != nil {
+				want = ""
+			}
+			if accessToken, _ := tokens.current(); accessToken != want {
+				t.Errorf("got token %q, want %q", accessToken, want)
+			}
+		})
+	}
+}
+
+func TestStoreWritesCacheCredentials(t *testing.T) {
+	t.Setenv(tokenCacheKeyEnv, "")
+
+	var calls int32
+	tokens := newTestTokenManager(t, tokenHandler(&calls))
+	tokens.clientId = "client"
+	tokens.customerId = "customer"
+	tokens.cacheFile = filepath.Join(t.TempDir(), "tokens.json")
+
+	if err := tokens.exchange(context.Background(), "access-old", "refresh-old"); err != nil {
+		t.Fatal(err)
+	}
+
+	cached, err := readTokenCache(tokens.cacheFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached.RefreshToken != "refresh-1" || cached.Endpoint != tokens.endpoint || cached.ClientID != "client" || cached.CustomerID != "customer" {
+		t.Errorf("got cache %+v", cached)
+	}
+}
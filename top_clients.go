@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type TopNClientResponse struct {
+	Clients []Client `json:"clients"`
+}
+
+type Client struct {
+	MacAddress  string `json:"macaddr"`
+	Name        string `json:"name"`
+	RxDataBytes int    `json:"rx_data_bytes"`
+	TxDataBytes int    `json:"tx_data_bytes"`
+}
+
+var (
+	clientRxDataBytes = prometheus.NewDesc("aruba_client_rx_data_bytes", "Volume of data received", []string{"name", "mac"}, nil)
+	clientTxDataBytes = prometheus.NewDesc("aruba_client_tx_data_bytes", "Volume of data transmitted", []string{"name", "mac"}, nil)
+)
+
+type topClientsCollector struct{}
+
+func init() {
+	registerCollector("top_clients", true, topClientsCollector{})
+}
+
+func (topClientsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- clientRxDataBytes
+	ch <- clientTxDataBytes
+}
+
+func (topClientsCollector) Update(ctx context.Context, client *CentralClient, ch chan<- prometheus.Metric) error {
+
+	query := url.Values{
+		"count": {"100"},
+	}
+	client.scope.apply(query)
+
+	var topNClientResponse TopNClientResponse
+	if err := client.Get(ctx, "monitoring/v1/clients/bandwidth_usage/topn", query, &topNClientResponse); err != nil {
+		return fmt.Errorf("listing top clients: %w", err)
+	}
+
+	for _, t := range topNClientResponse.Clients {
+
+		ch <- prometheus.MustNewConstMetric(clientRxDataBytes, prometheus.GaugeValue, float64(t.RxDataBytes), t.Name, t.MacAddress)
+		ch <- prometheus.MustNewConstMetric(clientTxDataBytes, prometheus.GaugeValue, float64(t.TxDataBytes), t.Name, t.MacAddress)
+
+	}
+
+	return nil
+}
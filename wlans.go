@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type Network struct {
+	ClientCount int    `json:"client_count"`
+	Essid       string `json:"essid"`
+	Security    string `json:"security"`
+	Type        string `json:"type"`
+}
+
+type NetworkResponse struct {
+	Networks []Network `json:"networks"`
+}
+
+type NetworkBandwidthResponse struct {
+	Samples []struct {
+		RxDataBytes int64 `json:"rx_data_bytes"`
+		TxDataBytes int64 `json:"tx_data_bytes"`
+		Timestamp   int64 `json:"timestamp"`
+	} `json:"samples"`
+}
+
+var wlansBandwidth = flag.Bool("collector.wlans.bandwidth", false, "Export the throughput of each WLAN, which costs one API call per SSID")
+
+var (
+	wlanInfo        = prometheus.NewDesc("aruba_wlan_info", "Security and type of the WLAN", []string{"ssid", "security", "type"}, nil)
+	wlanClientCount = prometheus.NewDesc("aruba_wlan_client_count", "Number of clients connected to the WLAN", []string{"ssid"}, nil)
+	wlanRxRate      = prometheus.NewDesc("aruba_wlan_rx_bytes_per_second", "Data received on the WLAN in bytes per second over the latest sample", []string{"ssid"}, nil)
+	wlanTxRate      = prometheus.NewDesc("aruba_wlan_tx_bytes_per_second", "Data transmitted on the WLAN in bytes per second over the latest sample", []string{"ssid"}, nil)
+)
+
+// wlanCollector reports each SSID across the whole tenant, or across the site
+// or group being probed.
+type wlanCollector struct{}
+
+func init() {
+	registerCollector("wlans", true, wlanCollector{})
+}
+
+func (wlanCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- wlanInfo
+	ch <- wlanClientCount
+	if *wlansBandwidth {
+		ch <- wlanRxRate
+		ch <- wlanTxRate
+	}
+}
+
+func (wlanCollector) Update(ctx context.Context, client *CentralClient, ch chan<- prometheus.Metric) error {
+
+	query := url.Values{
+		"calculate_client_count": {"true"},
+	}
+	client.scope.apply(query)
+
+	var networkResponse NetworkResponse
+	if err := client.Get(ctx, "monitoring/v2/networks", query, &networkResponse); err != nil {
+		return fmt.Errorf("listing WLANs: %w", err)
+	}
+
+	for _, n := range networkResponse.Networks {
+
+		ch <- prometheus.MustNewConstMetric(wlanInfo, prometheus.GaugeValue, 1, n.Essid, n.Security, n.Type)
+		ch <- prometheus.MustNewConstMetric(wlanClientCount, prometheus.GaugeValue, float64(n.ClientCount), n.Essid)
+
+		if !*wlansBandwidth {
+			continue
+		}
+
+		bandwidthQuery := url.Values{
+			"network": {n.Essid},
+		}
+		client.scope.apply(bandwidthQuery)
+
+		var bandwidthResponse NetworkBandwidthResponse
+		if err := client.Get(ctx, "monitoring/v1/networks/bandwidth_usage", bandwidthQuery, &bandwidthResponse); err != nil {
+			return fmt.Errorf("getting bandwidth of WLAN %s: %w", n.Essid, err)
+		}
+
+		// Each sample holds the bytes since the previous one.
+		samples := bandwidthResponse.Samples
+		if len(samples) < 2 {
+			continue
+		}
+		latest, previous := samples[len(samples)-1], samples[len(samples)-2]
+		if seconds := float64(latest.Timestamp - previous.Timestamp); seconds > 0 {
+			ch <- prometheus.MustNewConstMetric(wlanRxRate, prometheus.GaugeValue, float64(latest.RxDataBytes)/seconds, n.Essid)
+			ch <- prometheus.MustNewConstMetric(wlanTxRate, prometheus.GaugeValue, float64(latest.TxDataBytes)/seconds, n.Essid)
+		}
+	}
+
+	return nil
+}